@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Scenario is one line of batch input: the household flags, an optional id that's echoed
+// back, and the states of interest (all 51 if empty)
+type Scenario struct {
+	HouseholdInput
+	ID     json.RawMessage `json:"id,omitempty"`
+	States []string        `json:"states,omitempty"`
+}
+
+// BatchResult is one line of batch output, in the same order as the input. exactly one
+// of Error and Result is set.
+type BatchResult struct {
+	Line   int             `json:"line"`
+	ID     json.RawMessage `json:"id,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Result *Report         `json:"result,omitempty"`
+}
+
+type batchJob struct {
+	line   int
+	input  []byte
+	result chan []byte
+}
+
+func runBatch(args []string) {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of scenarios to calculate at once")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: taxify batch [flags] [file.jsonl]\nReads scenarios from stdin without a file.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *workers < 1 {
+		usageError("batch: -workers must be at least 1")
+	}
+	if fs.NArg() > 1 {
+		usageError("batch: takes at most one input file, got %v", fs.Args())
+	}
+
+	input := io.Reader(os.Stdin)
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			usageError("batch: %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	processBatch(input, out, *workers)
+}
+
+// processBatch calculates the scenarios on `workers` goroutines, writing the results in
+// input order as soon as each one and everything before it is done
+func processBatch(input io.Reader, out *bufio.Writer, workers int) {
+	jobs := make(chan *batchJob)
+	// pending holds the jobs in input order. its buffer bounds how far the workers can get
+	// ahead of the writer.
+	pending := make(chan *batchJob, 4*workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				job.result <- calcScenario(job.line, job.input)
+			}
+		}()
+	}
+	go func() {
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(make([]byte, 64*1024), maxRequestBytes)
+		for line := 1; scanner.Scan(); line++ {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			job := &batchJob{line, append([]byte(nil), scanner.Bytes()...), make(chan []byte, 1)}
+			pending <- job
+			jobs <- job
+		}
+		if err := scanner.Err(); err != nil {
+			// the writer reports it after every line read before it
+			job := &batchJob{result: make(chan []byte, 1)}
+			job.result <- encodeBatchResult(&BatchResult{Error: fmt.Sprintf("reading the input: %v", err)})
+			pending <- job
+		}
+		close(jobs)
+		close(pending)
+	}()
+
+	for job := range pending {
+		if _, err := out.Write(<-job.result); err != nil {
+			panic(err)
+		}
+		// flush as results arrive so that a slow census still streams
+		if len(pending) == 0 {
+			if err := out.Flush(); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+func calcScenario(line int, input []byte) []byte {
+	result := &BatchResult{Line: line}
+	scenario := &Scenario{}
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(scenario); err != nil {
+		result.Error = fmt.Sprintf("invalid JSON: %v", err)
+		return encodeBatchResult(result)
+	}
+	result.ID = scenario.ID
+	if err := scenario.validate(); err != nil {
+		result.Error = err.Error()
+		return encodeBatchResult(result)
+	}
+
+	household := scenario.household()
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	sortByEffectiveRate(states)
+	report := newReport(household, federal, states)
+	if len(scenario.States) > 0 {
+		wanted := make(map[string]bool, len(scenario.States))
+		for _, abbrev := range scenario.States {
+			if findState(states, abbrev) == nil {
+				result.Error = fmt.Sprintf("unknown state %q", abbrev)
+				return encodeBatchResult(result)
+			}
+			wanted[strings.ToUpper(abbrev)] = true
+		}
+		filtered := report.States[:0]
+		for _, state := range report.States {
+			if wanted[state.Abbrev] {
+				filtered = append(filtered, state)
+			}
+		}
+		report.States = filtered
+	}
+	result.Result = report
+	return encodeBatchResult(result)
+}
+
+// encodeBatchResult encodes one output line. a result JSON can't encode, like an infinite
+// amount, becomes the line's error rather than stopping the batch.
+func encodeBatchResult(result *BatchResult) []byte {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(result); err != nil {
+		buf.Reset()
+		failed := &BatchResult{Line: result.Line, ID: result.ID,
+			Error: fmt.Sprintf("the result can't be encoded as JSON: %v", err)}
+		if err := json.NewEncoder(&buf).Encode(failed); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
+}
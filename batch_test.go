@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestProcessBatchOrderAndErrors(t *testing.T) {
+	var input strings.Builder
+	want := map[int]string{} // line: the expected error, or "" for a result
+	for line := 1; line <= 300; line++ {
+		switch {
+		case line%50 == 0:
+			input.WriteString("   \n") // blank lines are skipped
+			continue
+		case line%37 == 0:
+			fmt.Fprintf(&input, `{"id": %d, "income": 50000`+"\n", line)
+			want[line] = "invalid JSON"
+		case line%41 == 0:
+			fmt.Fprintf(&input, `{"id": %d, "income": -5}`+"\n", line)
+			want[line] = "income"
+		case line%47 == 0:
+			fmt.Fprintf(&input, `{"id": %d, "income": 1e308, "cg": 1e308}`+"\n", line)
+			want[line] = "income must be at most"
+		case line%43 == 0:
+			fmt.Fprintf(&input, `{"id": %d, "income": 50000, "states": ["CA", "ZZ"]}`+"\n", line)
+			want[line] = `unknown state "ZZ"`
+		default:
+			// later lines are cheaper, so they'd finish first if order weren't kept
+			fmt.Fprintf(&input, `{"id": %d, "income": %d, "states": ["ca", "NY"]}`+"\n", line, 1000*(400-line))
+			want[line] = ""
+		}
+	}
+
+	var output bytes.Buffer
+	out := bufio.NewWriter(&output)
+	processBatch(strings.NewReader(input.String()), out, 8)
+	out.Flush()
+
+	previous := 0
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		var result BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("invalid output %q: %v", scanner.Text(), err)
+		}
+		if result.Line <= previous {
+			t.Fatalf("line %d came after line %d", result.Line, previous)
+		}
+		previous = result.Line
+		wantError, ok := want[result.Line]
+		if !ok {
+			t.Errorf("line %d: unexpected result for a blank line", result.Line)
+			continue
+		}
+		delete(want, result.Line)
+		if wantError == "" {
+			if result.Error != "" || result.Result == nil || len(result.Result.States) != 2 {
+				t.Errorf("line %d: got error %q and %v, want CA and NY", result.Line, result.Error, result.Result)
+			} else if income := result.Result.Inputs.Income; income != float64(1000*(400-result.Line)) {
+				t.Errorf("line %d: result for income %.0f, want %d", result.Line, income, 1000*(400-result.Line))
+			}
+		} else if !strings.Contains(result.Error, wantError) || result.Result != nil {
+			t.Errorf("line %d: got error %q, want one containing %q", result.Line, result.Error, wantError)
+		}
+		if wantError != "invalid JSON" && string(result.ID) != fmt.Sprint(result.Line) {
+			t.Errorf("line %d: id %s, want %d", result.Line, result.ID, result.Line)
+		}
+	}
+	for line := range want {
+		t.Errorf("line %d: no output", line)
+	}
+}
+
+func TestProcessBatchLineTooLong(t *testing.T) {
+	input := `{"income": 1}` + "\n" + `{"id": "` + strings.Repeat("x", maxRequestBytes) + `"}` + "\n"
+	var output bytes.Buffer
+	out := bufio.NewWriter(&output)
+	processBatch(strings.NewReader(input), out, 2)
+	out.Flush()
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"line":1`) || !strings.Contains(lines[1], "reading the input") {
+		t.Errorf("output = %.200q, want line 1's result, then the read error", output.String())
+	}
+}
+
+func TestEncodeBatchResultError(t *testing.T) {
+	report := &Report{Federal: FederalResult{EffectiveRate: math.Inf(1)}}
+	line := encodeBatchResult(&BatchResult{Line: 7, ID: json.RawMessage(`"a"`), Result: report})
+	var got BatchResult
+	if err := json.Unmarshal(line, &got); err != nil {
+		t.Fatalf("encodeBatchResult() = %q, not JSON: %v", line, err)
+	}
+	if got.Line != 7 || string(got.ID) != `"a"` || got.Result != nil || !strings.Contains(got.Error, "can't be encoded") {
+		t.Errorf("encodeBatchResult() = %s, want line 7's error", line)
+	}
+}
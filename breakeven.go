@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+)
+
+type BreakEven struct {
+	state  *State
+	salary float64 // the salary with the same after-tax income as in the home state
+}
+
+func runBreakEven(args []string) {
+	fs := flag.NewFlagSet("breakeven", flag.ExitOnError)
+	newHousehold := householdFlags(fs)
+	abbrev := fs.String("home", "", "Current state, e.g. CA; -income is the salary there, and the household's whole after-tax income there, other income included, is matched (required)")
+	fs.Parse(args)
+
+	household := newHousehold()
+	if household.income <= 0 {
+		usageError("breakeven: -income must be the salary in -home, e.g. -income=100000")
+	}
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	home := findState(states, *abbrev)
+	if home == nil {
+		usageError("breakeven: -home must be a state abbreviation, got %q", *abbrev)
+	}
+	breakEvens := calcBreakEvens(household, federal, home, states)
+	printBreakEvens(household, home, breakEvens)
+}
+
+// calcBreakEvens returns, for every state, the salary that leaves the household with the same
+// after-tax income as its salary does in `home`, cheapest first. other income stays the same
+// and counts toward the net after its tax in each state, as in calcGrossUp.
+func calcBreakEvens(household *Household, federal *Federal, home *State, states *[51]*State) []*BreakEven {
+	target := calcNetIncome(household, federal, home)
+	breakEvens := make([]*BreakEven, 0, len(states))
+	for _, state := range states {
+		state := state
+		salary := household.income
+		if state != home {
+			salary = solveGross(target, func(salary float64) float64 {
+				scenario := *household
+				scenario.income = salary
+				return calcNetIncome(&scenario, federal, state)
+			})
+		}
+		breakEvens = append(breakEvens, &BreakEven{state: state, salary: salary})
+	}
+	sort.SliceStable(breakEvens, func(i, j int) bool {
+		return breakEvens[i].salary < breakEvens[j].salary
+	})
+	return breakEvens
+}
+
+func printBreakEvens(household *Household, home *State, breakEvens []*BreakEven) {
+	fmt.Printf("\nSalary needed to match the after-tax income of $%.0f in %s\n", household.income, home.name)
+	fmt.Println("    State                Salary     Difference  Change")
+	fmt.Println("=======================================================")
+	for i, breakEven := range breakEvens {
+		difference := breakEven.salary - household.income
+		marker := fmt.Sprintf("%-3d", i+1)
+		if breakEven.state == home {
+			marker = "*  "
+		}
+		fmt.Printf("%s %-20s $%-9.0f %-11s %+.2f%%\n", marker, breakEven.state.name, breakEven.salary,
+			fmt.Sprintf("%+.0f", difference), 100*difference/household.income)
+	}
+	fmt.Println("=======================================================")
+}
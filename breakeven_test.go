@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestCalcBreakEvensMatchHomeNet(t *testing.T) {
+	household := &Household{income: 150000, capitalGains: 20000, treasuryInterest: 3000}
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	home := findState(states, "CA")
+	want := calcNetIncome(household, federal, home)
+	for _, breakEven := range calcBreakEvens(household, federal, home, states) {
+		scenario := *household
+		scenario.income = breakEven.salary
+		if net := calcNetIncome(&scenario, federal, breakEven.state); net < want || net > want+1 {
+			t.Errorf("%s: a salary of %.0f nets %.2f, want %.2f", breakEven.state.abbrev, breakEven.salary, net, want)
+		}
+	}
+}
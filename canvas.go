@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type TextAnchor int
+
+const (
+	anchorStart TextAnchor = iota
+	anchorMiddle
+	anchorEnd
+)
+
+// Canvas is what the charts draw on, so that one layout can be written as SVG or PNG.
+// coordinates are in pixels from the top left, and text is positioned by its baseline.
+type Canvas interface {
+	rect(x, y, w, h float64, fill color.RGBA)
+	line(x1, y1, x2, y2 float64, stroke color.RGBA, width float64, dashed bool)
+	polyline(points [][2]float64, stroke color.RGBA, width float64, dashed bool)
+	text(x, y float64, s string, size float64, fill color.RGBA, anchor TextAnchor)
+}
+
+var (
+	black     = color.RGBA{0, 0, 0, 255}
+	white     = color.RGBA{255, 255, 255, 255}
+	gray      = color.RGBA{120, 120, 120, 255}
+	lightGray = color.RGBA{225, 225, 225, 255}
+)
+
+// writeCanvas draws an image of the given size and writes it to filename, as SVG or PNG
+// depending on its extension
+func writeCanvas(filename string, width, height int, draw func(Canvas)) {
+	var data []byte
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".svg":
+		data = renderSVG(width, height, draw)
+	case ".png":
+		c := newRasterCanvas(width, height)
+		draw(c)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, c.img); err != nil {
+			panic(err)
+		}
+		data = buf.Bytes()
+	default:
+		usageError("%s: images must be .svg or .png", filename)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		panic(err)
+	}
+}
+
+// renderSVG draws an image of the given size as an SVG document
+func renderSVG(width, height int, draw func(Canvas)) []byte {
+	c := newSVGCanvas(width, height)
+	draw(c)
+	return c.bytes()
+}
+
+type SVGCanvas struct {
+	buf bytes.Buffer
+}
+
+func newSVGCanvas(width, height int) *SVGCanvas {
+	c := &SVGCanvas{}
+	fmt.Fprintf(&c.buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif">`+"\n",
+		width, height, width, height)
+	return c
+}
+
+// bytes closes the document and returns it
+func (c *SVGCanvas) bytes() []byte {
+	c.buf.WriteString("</svg>\n")
+	return c.buf.Bytes()
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func svgDash(dashed bool) string {
+	if dashed {
+		return ` stroke-dasharray="6 4"`
+	}
+	return ""
+}
+
+func (c *SVGCanvas) rect(x, y, w, h float64, fill color.RGBA) {
+	fmt.Fprintf(&c.buf, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`+"\n", x, y, w, h, svgColor(fill))
+}
+
+func (c *SVGCanvas) line(x1, y1, x2, y2 float64, stroke color.RGBA, width float64, dashed bool) {
+	fmt.Fprintf(&c.buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="%g"%s/>`+"\n",
+		x1, y1, x2, y2, svgColor(stroke), width, svgDash(dashed))
+}
+
+func (c *SVGCanvas) polyline(points [][2]float64, stroke color.RGBA, width float64, dashed bool) {
+	c.buf.WriteString(`<polyline fill="none" points="`)
+	for i, p := range points {
+		if i > 0 {
+			c.buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&c.buf, "%.1f,%.1f", p[0], p[1])
+	}
+	fmt.Fprintf(&c.buf, `" stroke="%s" stroke-width="%g" stroke-linejoin="round"%s/>`+"\n", svgColor(stroke), width, svgDash(dashed))
+}
+
+func (c *SVGCanvas) text(x, y float64, s string, size float64, fill color.RGBA, anchor TextAnchor) {
+	anchors := [...]string{"start", "middle", "end"}
+	fmt.Fprintf(&c.buf, `<text x="%.1f" y="%.1f" font-size="%g" fill="%s" text-anchor="%s">`,
+		x, y, size, svgColor(fill), anchors[anchor])
+	xml.EscapeText(&c.buf, []byte(s))
+	c.buf.WriteString("</text>\n")
+}
+
+// RasterCanvas draws without anti-aliasing, using a 5x7 bitmap font scaled to the text size
+type RasterCanvas struct {
+	img *image.RGBA
+}
+
+func newRasterCanvas(width, height int) *RasterCanvas {
+	return &RasterCanvas{image.NewRGBA(image.Rect(0, 0, width, height))}
+}
+
+func (c *RasterCanvas) rect(x, y, w, h float64, fill color.RGBA) {
+	for py := int(math.Round(y)); py < int(math.Round(y+h)); py++ {
+		for px := int(math.Round(x)); px < int(math.Round(x+w)); px++ {
+			c.img.SetRGBA(px, py, fill)
+		}
+	}
+}
+
+func (c *RasterCanvas) line(x1, y1, x2, y2 float64, stroke color.RGBA, width float64, dashed bool) {
+	c.stroke(x1, y1, x2, y2, stroke, width, dashed, 0)
+}
+
+func (c *RasterCanvas) polyline(points [][2]float64, stroke color.RGBA, width float64, dashed bool) {
+	// carry the distance along so dashes continue across the segments
+	distance := 0.0
+	for i := 1; i < len(points); i++ {
+		distance = c.stroke(points[i-1][0], points[i-1][1], points[i][0], points[i][1], stroke, width, dashed, distance)
+	}
+}
+
+// stroke stamps a disc of the line's width every half pixel, skipping the gaps of 6/4 dashes.
+// it returns the distance along the line drawn so far.
+func (c *RasterCanvas) stroke(x1, y1, x2, y2 float64, stroke color.RGBA, width float64, dashed bool,
+	distance float64) float64 {
+	length := math.Hypot(x2-x1, y2-y1)
+	r := math.Max(0.5, width/2)
+	for t := 0.0; t <= length; t += 0.5 {
+		if dashed && math.Mod(distance+t, 10) >= 6 {
+			continue
+		}
+		x, y := x1, y1
+		if length > 0 {
+			x, y = x1+(x2-x1)*t/length, y1+(y2-y1)*t/length
+		}
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				if dx*dx+dy*dy <= r*r+0.25 {
+					c.img.SetRGBA(int(math.Round(x+dx)), int(math.Round(y+dy)), stroke)
+				}
+			}
+		}
+	}
+	return distance + length
+}
+
+func (c *RasterCanvas) text(x, y float64, s string, size float64, fill color.RGBA, anchor TextAnchor) {
+	scale := math.Max(1, math.Round(size/8))
+	s = strings.ToUpper(s)
+	width := float64(6*len([]rune(s))-1) * scale
+	switch anchor {
+	case anchorMiddle:
+		x -= width / 2
+	case anchorEnd:
+		x -= width
+	}
+	top := y - 7*scale
+	for i, r := range []rune(s) {
+		glyph, ok := font5x7[r]
+		if !ok {
+			glyph = font5x7['?']
+		}
+		for row, bits := range glyph {
+			for col := 0; col < 5; col++ {
+				if bits&(0x10>>col) != 0 {
+					c.rect(x+float64(6*i+col)*scale, top+float64(row)*scale, scale, scale, fill)
+				}
+			}
+		}
+	}
+}
+
+// font5x7 has the characters the charts use. each row's low 5 bits are its pixels, left to right.
+var font5x7 = map[rune][7]uint8{
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E}, '1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F}, '3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02}, '5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E}, '7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E}, '9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'A': {0x0E, 0x11, 0x11, 0x11, 0x1F, 0x11, 0x11}, 'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E}, 'D': {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F}, 'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F}, 'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E}, 'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11}, 'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11}, 'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E}, 'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D}, 'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S': {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E}, 'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E}, 'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A}, 'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04}, 'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	' ': {}, '.': {0, 0, 0, 0, 0, 0x0C, 0x0C}, ',': {0, 0, 0, 0, 0x0C, 0x04, 0x08},
+	'-': {0, 0, 0, 0x1F, 0, 0, 0}, '+': {0, 0x04, 0x04, 0x1F, 0x04, 0x04, 0},
+	'%': {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03}, '$': {0x04, 0x0F, 0x14, 0x0E, 0x05, 0x1E, 0x04},
+	'(': {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02}, ')': {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'/': {0, 0x01, 0x02, 0x04, 0x08, 0x10, 0}, ':': {0, 0x0C, 0x0C, 0, 0x0C, 0x0C, 0},
+	'\'': {0x0C, 0x04, 0x08, 0, 0, 0, 0}, '&': {0x0C, 0x12, 0x14, 0x08, 0x15, 0x12, 0x0D},
+	'*': {0, 0x04, 0x15, 0x0E, 0x15, 0x04, 0}, '?': {0x0E, 0x11, 0x01, 0x02, 0x04, 0, 0x04},
+}
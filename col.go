@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type CostOfLiving struct {
+	area          string // a metro area, or the state's name for state-level indexes
+	state         *State
+	index         float64 // 100 is the national average
+	afterTax      float64
+	purchasePower float64 // after-tax income in national average dollars
+}
+
+// readCostOfLiving reads a cost of living index CSV with a header row. the `state` (an
+// abbreviation) and `index` columns are required. an optional `area` column names metro
+// areas, so that several rows can share a state.
+func readCostOfLiving(filename string, states *[51]*State) []*CostOfLiving {
+	file, err := os.Open(filename)
+	if err != nil {
+		usageError("-col: %v", err)
+	}
+	defer file.Close()
+	rows, err := parseCostOfLiving(file, states)
+	if err != nil {
+		usageError("-col: %s: %v", filename, err)
+	}
+	return rows
+}
+
+func parseCostOfLiving(input io.Reader, states *[51]*State) ([]*CostOfLiving, error) {
+	r := csv.NewReader(input)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading the header: %v", err)
+	}
+	columns := map[string]int{"area": -1, "state": -1, "index": -1}
+	for i, name := range header {
+		if _, ok := columns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+	}
+	if columns["state"] < 0 || columns["index"] < 0 {
+		return nil, fmt.Errorf("needs `state` and `index` columns, got %v", header)
+	}
+
+	var rows []*CostOfLiving
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		state := findState(states, strings.TrimSpace(record[columns["state"]]))
+		if state == nil {
+			return nil, fmt.Errorf("line %d: unknown state %q", line, record[columns["state"]])
+		}
+		index, err := strconv.ParseFloat(strings.TrimSpace(record[columns["index"]]), 64)
+		if err != nil || !(index > 0) || math.IsInf(index, 1) {
+			return nil, fmt.Errorf("line %d: invalid index %q", line, record[columns["index"]])
+		}
+		area := state.name
+		if columns["area"] >= 0 && strings.TrimSpace(record[columns["area"]]) != "" {
+			area = strings.TrimSpace(record[columns["area"]])
+		}
+		rows = append(rows, &CostOfLiving{area: area, state: state, index: index})
+	}
+	return rows, nil
+}
+
+// rankByPurchasingPower fills in each row's after-tax income and purchasing power and
+// sorts the rows by purchasing power, highest first.
+func rankByPurchasingPower(household *Household, federal *Federal, rows []*CostOfLiving) {
+	for _, row := range rows {
+		row.afterTax = calcNetIncome(household, federal, row.state)
+		row.purchasePower = row.afterTax * 100 / row.index
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].purchasePower > rows[j].purchasePower
+	})
+}
+
+func printCostOfLiving(household *Household, rows []*CostOfLiving) {
+	fmt.Printf("\nCost of living adjusted report for income of $%.0f\n", household.income)
+	fmt.Println("    Area                       State  After-tax  COL index  Adjusted")
+	fmt.Println("=======================================================================")
+	for i, row := range rows {
+		fmt.Printf("%-3d %-26s %-6s $%-9.0f %-10.1f $%.0f\n", i+1, truncate(row.area, 26),
+			row.state.abbrev, row.afterTax, row.index, row.purchasePower)
+	}
+	fmt.Println("=======================================================================")
+}
+
+// truncate shortens `s` to at most `n` characters.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCostOfLiving(t *testing.T) {
+	states := testStates()
+	input := "Area,State,Index\nAustin,TX,120\n,ca,150.5\n  ,NY , 100\n"
+	rows, err := parseCostOfLiving(strings.NewReader(input), states)
+	if err != nil {
+		t.Fatalf("parseCostOfLiving() = %v", err)
+	}
+	want := []struct {
+		area   string
+		abbrev string
+		index  float64
+	}{{"Austin", "TX", 120}, {"California", "CA", 150.5}, {"New York", "NY", 100}}
+	if len(rows) != len(want) {
+		t.Fatalf("parseCostOfLiving() returned %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		if row.area != want[i].area || row.state.abbrev != want[i].abbrev || row.index != want[i].index {
+			t.Errorf("row %d = %s, %s, %g, want %+v", i+1, row.area, row.state.abbrev, row.index, want[i])
+		}
+	}
+
+	// the area column is optional and the columns can be in any order
+	rows, err = parseCostOfLiving(strings.NewReader("index,state\n95,WA\n"), states)
+	if err != nil || len(rows) != 1 || rows[0].area != "Washington" || rows[0].index != 95 {
+		t.Errorf("without an area column: parseCostOfLiving() = %v, %v", rows, err)
+	}
+}
+
+func TestParseCostOfLivingErrors(t *testing.T) {
+	states := testStates()
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", "reading the header"},
+		{"no index column", "state,cost\nTX,100\n", "needs `state` and `index` columns"},
+		{"no state column", "area,index\nAustin,100\n", "needs `state` and `index` columns"},
+		{"unknown state", "state,index\nTX,100\nXX,100\n", `line 3: unknown state "XX"`},
+		{"not a number", "state,index\nTX,high\n", `line 2: invalid index "high"`},
+		{"zero", "state,index\nTX,100\nCA,0\n", `line 3: invalid index "0"`},
+		{"negative", "state,index\nTX,-5\n", `line 2: invalid index "-5"`},
+		{"not finite", "state,index\nTX,NaN\n", `line 2: invalid index "NaN"`},
+		{"infinite", "state,index\nTX,Inf\n", `line 2: invalid index "Inf"`},
+		{"a short row", "state,index\nTX\n", "wrong number of fields"},
+	}
+	for _, test := range tests {
+		_, err := parseCostOfLiving(strings.NewReader(test.input), states)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: parseCostOfLiving() = %v, want an error containing %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestRankByPurchasingPower(t *testing.T) {
+	household := &Household{income: 100000}
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	input := "area,state,index\nSan Francisco,CA,180\nHouston,TX,95\nAustin,TX,120\nRural Texas,TX,95\nBuffalo,NY,95\n"
+	rows, err := parseCostOfLiving(strings.NewReader(input), states)
+	if err != nil {
+		t.Fatalf("parseCostOfLiving() = %v", err)
+	}
+	rankByPurchasingPower(household, federal, rows)
+
+	// Texas has no income tax, so at the same index it's ahead of New York; ties keep the file's order
+	var got []string
+	for i, row := range rows {
+		got = append(got, row.area)
+		if want := row.afterTax * 100 / row.index; !within(row.purchasePower, want) {
+			t.Errorf("%s: purchasing power = %.2f, want %.2f", row.area, row.purchasePower, want)
+		}
+		if i > 0 && row.purchasePower > rows[i-1].purchasePower {
+			t.Errorf("%s ranks below %s with more purchasing power", row.area, rows[i-1].area)
+		}
+	}
+	if want := "Houston,Rural Texas,Buffalo,Austin,San Francisco"; strings.Join(got, ",") != want {
+		t.Errorf("ranking = %s, want %s", strings.Join(got, ","), want)
+	}
+	if rows[0].afterTax != calcNetIncome(household, federal, findState(states, "TX")) {
+		t.Errorf("Houston after-tax = %.2f, want the Texas net income", rows[0].afterTax)
+	}
+}
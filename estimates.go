@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type EstimatedTaxRules struct {
+	// the required annual payment is the lesser of `currentYearShare` of this year's tax
+	// and `priorYearShare` (or `highIncomeShare` above `highIncomeAGI` last year) of last year's.
+	currentYearShare  float64
+	priorYearShare    float64
+	highIncomeShare   float64
+	highIncomeAGI     int
+	priorYearAGILimit int       // the prior year safe harbor isn't available at this year's AGI or above; 0 means no limit
+	installments      []float64 // share of the required payment due on each of installmentDueDates
+	minimumOwed       int       // no payments are required when tax less withholding is under this
+	penaltyRate       float64   // annual underpayment interest rate
+	// withholding is treated as paid in equal amounts on the due dates, as federally, unless this is
+	// set and it's treated as paid in the installments' shares, as in CA
+	withholdingByInstallment bool
+}
+
+var defaultStateEstimates = EstimatedTaxRules{
+	currentYearShare: 0.9,
+	priorYearShare:   1.0,
+	highIncomeShare:  1.1,
+	highIncomeAGI:    150000,
+	installments:     []float64{0.25, 0.25, 0.25, 0.25},
+	minimumOwed:      500,
+	penaltyRate:      0.05,
+}
+
+type EstimatedPayments struct {
+	name         string
+	tax          float64 // this year's income tax
+	requiredPaid float64 // the safe harbor amount to pay in through withholding and estimates
+	safeHarbor   string
+	withholding  float64
+	installments []float64 // required estimated payments, by quarter
+	paid         []float64 // estimated payments made, by quarter
+	penalty      float64
+}
+
+// installmentDueDates returns the due dates of the four quarterly payments, followed by the filing deadline.
+// every state's installments fall on the federal due dates.
+func installmentDueDates() []time.Time {
+	return []time.Time{
+		time.Date(taxYear, time.April, 15, 0, 0, 0, 0, time.UTC),
+		time.Date(taxYear, time.June, 15, 0, 0, 0, 0, time.UTC),
+		time.Date(taxYear, time.September, 15, 0, 0, 0, 0, time.UTC),
+		time.Date(taxYear+1, time.January, 15, 0, 0, 0, 0, time.UTC),
+		time.Date(taxYear+1, time.April, 15, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func runEstimates(args []string) {
+	fs := flag.NewFlagSet("estimates", flag.ExitOnError)
+	newHousehold := householdFlags(fs)
+	abbrev := fs.String("state", "", "State of residence, e.g. CA (required)")
+	priorReturn := fs.Bool("priorReturn", false, "There was a full-year return last year, so its tax is a safe harbor")
+	priorAGI := fs.Float64("priorAGI", 0, "Last year's AGI (with -priorReturn)")
+	priorFederalTax := fs.Float64("priorFederalTax", 0, "Last year's federal income tax (with -priorReturn)")
+	priorStateTax := fs.Float64("priorStateTax", 0, "Last year's state income tax (with -priorReturn)")
+	federalWithholding := fs.Float64("federalWithholding", 0, "Federal income tax withheld for the year")
+	stateWithholding := fs.Float64("stateWithholding", 0, "State income tax withheld for the year")
+	federalPaid := fs.String("federalPaid", "", "Federal estimated payments made each quarter, e.g. 2000,2000,0,0")
+	statePaid := fs.String("statePaid", "", "State estimated payments made each quarter, e.g. 500,500,0,0")
+	fs.Parse(args)
+
+	household := newHousehold()
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	state := findState(states, *abbrev)
+	if state == nil {
+		usageError("estimates: -state must be a state abbreviation, got %q", *abbrev)
+	}
+	if !*priorReturn && (*priorAGI != 0 || *priorFederalTax != 0 || *priorStateTax != 0) {
+		usageError("estimates: -priorAGI, -priorFederalTax and -priorStateTax need -priorReturn")
+	}
+
+	federalTax := float64(federal.incomeTax) - federal.calcPayrollTax(household)
+	stateTax := float64(state.incomeTax)
+	printEstimates(household, []*EstimatedPayments{
+		calcEstimatedPayments(federal.name, &federal.estimates, federalTax, federal.calcAGI(household),
+			*priorReturn, *priorFederalTax, *priorAGI,
+			*federalWithholding, parseQuarterly("-federalPaid", *federalPaid)),
+		calcEstimatedPayments(state.name, state.estimatedTaxRules(), stateTax, state.calcAGI(household, federal),
+			*priorReturn, *priorStateTax, *priorAGI,
+			*stateWithholding, parseQuarterly("-statePaid", *statePaid)),
+	})
+}
+
+// estimatedTaxRules returns the state's rules, or the common ones if it doesn't set its own.
+func (state *State) estimatedTaxRules() *EstimatedTaxRules {
+	if state.estimates.installments == nil {
+		return &defaultStateEstimates
+	}
+	return &state.estimates
+}
+
+// parseQuarterly parses up to four comma separated amounts, one per quarter.
+func parseQuarterly(name, value string) []float64 {
+	quarters := make([]float64, 4)
+	if value == "" {
+		return quarters
+	}
+	fields := strings.Split(value, ",")
+	if len(fields) > 4 {
+		usageError("estimates: %s takes at most 4 quarterly amounts, got %q", name, value)
+	}
+	for i, field := range fields {
+		amount, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			usageError("estimates: %s must be comma separated amounts, got %q", name, value)
+		}
+		quarters[i] = amount
+	}
+	return quarters
+}
+
+// calcEstimatedPayments returns the required payment for each installment under the safe harbor
+// rules and the underpayment penalty for the payments made. `agi` is this year's. without a
+// full-year return last year, only this year's tax is a safe harbor; with one, no tax last year
+// means none has to be paid in.
+func calcEstimatedPayments(name string, rules *EstimatedTaxRules, tax, agi float64, priorReturn bool,
+	priorTax, priorAGI, withholding float64, paid []float64) *EstimatedPayments {
+	dueDates := installmentDueDates()
+	if len(rules.installments) != len(dueDates)-1 {
+		panic(fmt.Sprintf("%s has %d installments, not one per due date", name, len(rules.installments)))
+	}
+	if len(paid) > len(rules.installments) {
+		panic(fmt.Sprintf("%d payments for %d installments", len(paid), len(rules.installments)))
+	}
+	estimates := &EstimatedPayments{
+		name:         name,
+		tax:          tax,
+		withholding:  withholding,
+		installments: make([]float64, len(rules.installments)),
+		paid:         make([]float64, len(rules.installments)),
+	}
+	copy(estimates.paid, paid)
+	if tax-withholding < float64(rules.minimumOwed) {
+		estimates.safeHarbor = fmt.Sprintf("under $%d owed", rules.minimumOwed)
+		return estimates
+	}
+
+	estimates.requiredPaid = tax * rules.currentYearShare
+	estimates.safeHarbor = fmt.Sprintf("%.0f%% of this year's tax", 100*rules.currentYearShare)
+	priorYearAllowed := rules.priorYearAGILimit == 0 || agi < float64(rules.priorYearAGILimit)
+	if priorReturn && priorYearAllowed {
+		priorShare := rules.priorYearShare
+		if rules.highIncomeShare > 0 && priorAGI > float64(rules.highIncomeAGI) {
+			priorShare = rules.highIncomeShare
+		}
+		if priorTax*priorShare < estimates.requiredPaid {
+			estimates.requiredPaid = math.Max(0, priorTax*priorShare)
+			estimates.safeHarbor = fmt.Sprintf("%.0f%% of last year's tax", 100*priorShare)
+			if priorTax <= 0 {
+				estimates.safeHarbor = "no tax last year"
+			}
+		}
+	}
+
+	// the penalty accrues on the cumulative shortfall between each due date and the next
+	required, madeGood := 0.0, 0.0
+	for i, share := range rules.installments {
+		withheld := withholding / float64(len(rules.installments))
+		if rules.withholdingByInstallment {
+			withheld = withholding * share
+		}
+		estimates.installments[i] = math.Max(0, estimates.requiredPaid*share-withheld)
+		required += estimates.requiredPaid * share
+		madeGood += withheld + estimates.paid[i]
+		days := dueDates[i+1].Sub(dueDates[i]).Hours() / 24
+		estimates.penalty += math.Max(0, required-madeGood) * rules.penaltyRate * days / 365
+	}
+	return estimates
+}
+
+func printEstimates(household *Household, allEstimates []*EstimatedPayments) {
+	dueDates := installmentDueDates()
+	fmt.Printf("\nEstimated tax payments for %d on income of $%.0f\n", taxYear, household.income)
+	for _, estimates := range allEstimates {
+		fmt.Println("==================================================")
+		fmt.Printf("%s income tax: $%.0f, withheld: $%.0f\n", estimates.name, estimates.tax, estimates.withholding)
+		fmt.Printf("Required to pay in: $%.0f (%s)\n", estimates.requiredPaid, estimates.safeHarbor)
+		fmt.Println("    Due            Required   Paid")
+		for i, amount := range estimates.installments {
+			fmt.Printf("    %-14s $%-9.0f $%.0f\n", dueDates[i].Format("Jan 2, 2006"), amount, estimates.paid[i])
+		}
+		fmt.Printf("Estimated underpayment penalty: $%.0f\n", estimates.penalty)
+	}
+	fmt.Println("==================================================")
+}
@@ -0,0 +1,87 @@
+package main
+
+import "testing"
+
+func TestEstimatedSafeHarbor(t *testing.T) {
+	federal := initializeFederal(&Household{})
+	states := testStates()
+	ca, ma, ct := findState(states, "CA"), findState(states, "MA"), findState(states, "CT")
+	tests := []struct {
+		name        string
+		rules       *EstimatedTaxRules
+		tax         float64
+		agi         float64
+		priorReturn bool
+		priorTax    float64
+		priorAGI    float64
+		withholding float64
+		want        float64
+		safeHarbor  string
+	}{
+		{"no return last year", &federal.estimates, 20000, 150000, false, 0, 0, 0, 18000, "90% of this year's tax"},
+		{"last year's tax", &federal.estimates, 20000, 150000, true, 15000, 100000, 0, 15000, "100% of last year's tax"},
+		{"110% over $150,000 of AGI last year", &federal.estimates, 20000, 150000, true, 15000, 200000, 0,
+			16500, "110% of last year's tax"},
+		{"110% of last year's is more", &federal.estimates, 20000, 150000, true, 17000, 200000, 0,
+			18000, "90% of this year's tax"},
+		{"no tax last year", &federal.estimates, 20000, 150000, true, 0, 30000, 0, 0, "no tax last year"},
+		{"under the minimum owed", &federal.estimates, 1500, 20000, false, 0, 0, 600, 0, "under $1000 owed"},
+		{"MA takes 80% of this year's", ma.estimatedTaxRules(), 10000, 150000, false, 0, 0, 0, 8000,
+			"80% of this year's tax"},
+		{"CT's last year share doesn't rise", ct.estimatedTaxRules(), 10000, 250000, true, 5000, 200000, 0,
+			5000, "100% of last year's tax"},
+		{"CA's limit is on this year's AGI", ca.estimatedTaxRules(), 100000, 1.2e6, true, 50000, 900000, 0,
+			90000, "90% of this year's tax"},
+		{"CA under the limit this year", ca.estimatedTaxRules(), 100000, 900000, true, 50000, 1.2e6, 0,
+			55000, "110% of last year's tax"},
+		{"states use the common rules", findState(states, "OH").estimatedTaxRules(), 4000, 80000, false, 0, 0, 0,
+			3600, "90% of this year's tax"},
+	}
+	for _, test := range tests {
+		got := calcEstimatedPayments(test.name, test.rules, test.tax, test.agi, test.priorReturn, test.priorTax,
+			test.priorAGI, test.withholding, nil)
+		if !within(got.requiredPaid, test.want) || got.safeHarbor != test.safeHarbor {
+			t.Errorf("%s: required %.2f (%s), want %.2f (%s)", test.name, got.requiredPaid, got.safeHarbor,
+				test.want, test.safeHarbor)
+		}
+	}
+}
+
+func TestEstimatedInstallmentsAndPenalty(t *testing.T) {
+	federal := initializeFederal(&Household{})
+	ca := findState(testStates(), "CA")
+	tests := []struct {
+		name         string
+		rules        *EstimatedTaxRules
+		tax          float64
+		withholding  float64
+		paid         []float64
+		installments []float64
+		penalty      float64
+	}{
+		// the shortfall is $2,250 more each quarter, for 61, 92, 122 and 90 days at 5%
+		{"nothing paid", &federal.estimates, 10000, 0, nil, []float64{2250, 2250, 2250, 2250},
+			0.05 / 365 * (2250*61 + 4500*92 + 6750*122 + 9000*90)},
+		{"paid on time", &federal.estimates, 10000, 0, []float64{2250, 2250, 2250, 2250},
+			[]float64{2250, 2250, 2250, 2250}, 0},
+		{"the first quarter paid late", &federal.estimates, 10000, 0, []float64{0, 4500, 2250, 2250},
+			[]float64{2250, 2250, 2250, 2250}, 0.05 / 365 * 2250 * 61},
+		{"withholding is paid evenly", &federal.estimates, 20000, 8000, nil, []float64{2500, 2500, 2500, 2500},
+			0.05 / 365 * (2500*61 + 5000*92 + 7500*122 + 10000*90)},
+		{"CA's installments", ca.estimatedTaxRules(), 10000, 0, nil, []float64{2700, 3600, 0, 2700},
+			0.05 / 365 * (2700*61 + 6300*92 + 6300*122 + 9000*90)},
+		{"CA's withholding is paid like its installments", ca.estimatedTaxRules(), 10000, 9000, nil,
+			[]float64{0, 0, 0, 0}, 0},
+	}
+	for _, test := range tests {
+		got := calcEstimatedPayments(test.name, test.rules, test.tax, 100000, false, 0, 0, test.withholding, test.paid)
+		for i, want := range test.installments {
+			if !within(got.installments[i], want) {
+				t.Errorf("%s: installment %d = %.2f, want %.2f", test.name, i+1, got.installments[i], want)
+			}
+		}
+		if !within(got.penalty, test.penalty) {
+			t.Errorf("%s: penalty = %.2f, want %.2f", test.name, got.penalty, test.penalty)
+		}
+	}
+}
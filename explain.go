@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// Worksheet records the steps of a state's calculation. a nil *Worksheet records nothing,
+// so calcBreakdown can take one without slowing down the modes that don't want it.
+type Worksheet struct {
+	Lines []WorksheetLine `json:"lines"`
+}
+
+// WorksheetLine is one step. Amount is its effect: negative for deductions from taxable
+// income and credits against tax. TaxableIncome and Tax are the running totals after it.
+type WorksheetLine struct {
+	Step          string  `json:"step"`
+	Amount        float64 `json:"amount"`
+	Base          float64 `json:"base,omitempty"` // the income a rate applies to
+	Rate          float64 `json:"rate,omitempty"`
+	TaxableIncome float64 `json:"taxableIncome"`
+	Tax           float64 `json:"tax"`
+}
+
+// Explanation is the JSON form of the explain worksheet
+type Explanation struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	TaxYear       int             `json:"taxYear"`
+	Inputs        HouseholdInput  `json:"inputs"`
+	State         string          `json:"state"`
+	Abbrev        string          `json:"abbrev"`
+	Lines         []WorksheetLine `json:"lines"`
+	Tax           int             `json:"tax"`
+	EffectiveRate float64         `json:"effectiveRate"`
+}
+
+// add records a step, skipping the ones that don't apply
+func (w *Worksheet) add(step string, amount, taxableIncome, tax float64) {
+	if w == nil || amount == 0 {
+		return
+	}
+	w.Lines = append(w.Lines, WorksheetLine{Step: step, Amount: amount, TaxableIncome: taxableIncome, Tax: tax})
+}
+func (w *Worksheet) addf(amount, taxableIncome, tax float64, format string, args ...interface{}) {
+	if w == nil {
+		return
+	}
+	w.add(fmt.Sprintf(format, args...), amount, taxableIncome, tax)
+}
+
+// addRate records income taxed at its own flat rate
+func (w *Worksheet) addRate(step string, base, rate, taxableIncome, tax float64) {
+	if w == nil || base*rate == 0 {
+		return
+	}
+	w.Lines = append(w.Lines, WorksheetLine{
+		Step:          fmt.Sprintf("%s at %.3f%%", step, 100*rate),
+		Amount:        base * rate,
+		Base:          base,
+		Rate:          rate,
+		TaxableIncome: taxableIncome,
+		Tax:           tax,
+	})
+}
+
+// addBrackets records each bracket's slice of taxable income the way taxEngine taxes it.
+// tax is the running tax before the brackets.
+func (w *Worksheet) addBrackets(taxableIncome float64, brackets []int, rates []float64, tax float64) {
+	if w == nil {
+		return
+	}
+	for i, bracket := range brackets {
+		slice, label := taxableIncome-float64(bracket), fmt.Sprintf("over $%d", bracket)
+		if i < len(brackets)-1 {
+			slice = math.Min(float64(brackets[i+1]-bracket), slice)
+			label = fmt.Sprintf("$%d to $%d", bracket, brackets[i+1])
+		}
+		if slice <= 0 || rates[i] == 0 {
+			continue
+		}
+		tax += slice * rates[i]
+		w.Lines = append(w.Lines, WorksheetLine{
+			Step:          fmt.Sprintf("bracket %s at %.3f%%", label, 100*rates[i]),
+			Amount:        slice * rates[i],
+			Base:          slice,
+			Rate:          rates[i],
+			TaxableIncome: taxableIncome,
+			Tax:           tax,
+		})
+	}
+}
+
+func runExplain(args []string) {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	newHousehold := householdFlags(fs)
+	abbrev := fs.String("state", "", "State to explain, e.g. CA (required)")
+	format := fs.String("format", "text", "Output format: text or json")
+	fs.Parse(args)
+	if *format != "text" && *format != "json" {
+		usageError("explain: -format must be text or json, got %q", *format)
+	}
+
+	household := newHousehold()
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	state := findState(states, *abbrev)
+	if state == nil {
+		usageError("explain: -state must be a state abbreviation, got %q", *abbrev)
+	}
+
+	worksheet := &Worksheet{}
+	b := state.calcBreakdown(household, federal, worksheet)
+	if *format == "json" {
+		writeJSON(os.Stdout, &Explanation{
+			SchemaVersion: reportSchemaVersion,
+			TaxYear:       taxYear,
+			Inputs:        newHouseholdInput(household),
+			State:         state.name,
+			Abbrev:        state.abbrev,
+			Lines:         worksheet.Lines,
+			Tax:           int(b.tax),
+			EffectiveRate: effectiveRate(b.tax, b.grossIncome),
+		})
+		return
+	}
+	printWorksheet(household, state, worksheet, b)
+}
+
+func printWorksheet(household *Household, state *State, worksheet *Worksheet, b *StateBreakdown) {
+	fmt.Println()
+	for _, line := range worksheetLines(household, state, worksheet, b) {
+		fmt.Println(line)
+	}
+}
+
+func worksheetLines(household *Household, state *State, worksheet *Worksheet, b *StateBreakdown) []string {
+	rule := strings.Repeat("=", 86)
+	lines := []string{
+		fmt.Sprintf("%s income tax worksheet for income of $%.0f", state.name, household.income),
+		"    Step                                              Amount   Taxable Income      Tax",
+		rule,
+	}
+	for i, line := range worksheet.Lines {
+		lines = append(lines, fmt.Sprintf("%-3d %-46s %11.2f %16.2f %10.2f", i+1, line.Step, line.Amount, line.TaxableIncome, line.Tax))
+	}
+	return append(lines, rule,
+		fmt.Sprintf("    %-46s %11s %16s %10d", "Tax", "", "", int(b.tax)),
+		fmt.Sprintf("    %-46s %11s %16s %9.3f%%", "Effective rate", "", "", 100*effectiveRate(b.tax, b.grossIncome)))
+}
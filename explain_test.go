@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWorksheetLines(t *testing.T) {
+	household := &Household{income: 100000}
+	federal := initializeFederal(household)
+	il := findState(initializeStates(household, federal), "IL")
+	worksheet := &Worksheet{}
+	b := il.calcBreakdown(household, federal, worksheet)
+	// IL taxes income over its $2,375 exemption at 4.95%
+	want := []string{
+		"Illinois income tax worksheet for income of $100000",
+		"    Step                                              Amount   Taxable Income      Tax",
+		strings.Repeat("=", 86),
+		"1   gross income                                     100000.00             0.00       0.00",
+		"2   ordinary income                                  100000.00        100000.00       0.00",
+		"3   personal exemption                                -2375.00         97625.00       0.00",
+		"4   bracket over $0 at 4.950%                          4832.44         97625.00    4832.44",
+		strings.Repeat("=", 86),
+		"    Tax                                                                               4832",
+		"    Effective rate                                                                  4.832%",
+	}
+	got := worksheetLines(household, il, worksheet, b)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("worksheetLines() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWorksheetEndsAtTheTax(t *testing.T) {
+	households := []*Household{
+		{income: 30000, numDependents: 2},
+		{income: 85000, capitalGains: 10000, dividends: 3000},
+		{income: 250000, mfj: true, numDependents: 1, contributions401k: 20000},
+		{income: 40000, age: 70, pension: 30000, socialSecurity: 25000},
+		{income: 1.5e6, capitalGains: 500000, dividends: 50000, qualified: true},
+	}
+	for _, household := range households {
+		federal := initializeFederal(household)
+		for _, state := range initializeStates(household, federal) {
+			worksheet := &Worksheet{}
+			b := state.calcBreakdown(household, federal, worksheet)
+			tax := 0.0
+			if n := len(worksheet.Lines); n > 0 {
+				tax = worksheet.Lines[n-1].Tax
+			}
+			if !within(tax, b.tax) {
+				t.Errorf("%s at %.0f: the worksheet ends at a tax of %.2f, want %.2f",
+					state.abbrev, household.income, tax, b.tax)
+			}
+			for _, line := range worksheet.Lines {
+				if line.Step == "" || line.Amount == 0 {
+					t.Errorf("%s at %.0f: a step that doesn't apply was recorded: %+v", state.abbrev, household.income, line)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+)
+
+type GrossUp struct {
+	state *State
+	gross float64 // the required income, or bonus on top of the base income
+	tax   float64 // federal, payroll and state tax on the gross
+}
+
+func runGrossUp(args []string) {
+	fs := flag.NewFlagSet("grossup", flag.ExitOnError)
+	newHousehold := householdFlags(fs)
+	target := fs.Float64("net", 0, "The after-tax income to reach: the household's whole net, other income included, or with -bonus what the bonus adds (required)")
+	bonus := fs.Bool("bonus", false, "Solve for a bonus on top of -income instead of the whole income")
+	fs.Parse(args)
+	if *target <= 0 {
+		usageError("grossup: -net must be positive, e.g. -net=100000")
+	}
+
+	household := newHousehold()
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	grossUps := make([]*GrossUp, 0, len(states))
+	for _, state := range states {
+		grossUps = append(grossUps, calcGrossUp(household, federal, state, *target, *bonus))
+	}
+	sort.SliceStable(grossUps, func(i, j int) bool {
+		return grossUps[i].gross < grossUps[j].gross
+	})
+	printGrossUps(household, grossUps, *target, *bonus)
+}
+
+// calcGrossUp solves for the ordinary income (or bonus on top of it) that nets `target`
+// after federal, payroll and state tax in the given state. the net is the household's whole
+// after-tax income, so other income counts toward it, as in calcBreakEvens. a bonus only
+// counts what it adds to the net.
+func calcGrossUp(household *Household, federal *Federal, state *State, target float64, bonus bool) *GrossUp {
+	base := 0.0
+	if bonus {
+		base = household.income
+	}
+	withIncome := func(gross float64) *Household {
+		scenario := *household
+		scenario.income = base + gross
+		return &scenario
+	}
+	baseNet := 0.0
+	if bonus {
+		baseNet = calcNetIncome(withIncome(0), federal, state)
+	}
+	gross := solveGross(target, func(gross float64) float64 {
+		return calcNetIncome(withIncome(gross), federal, state) - baseNet
+	})
+	return &GrossUp{state: state, gross: gross, tax: gross - target}
+}
+
+// calcNetIncome returns the household's income after federal, payroll and state tax.
+func calcNetIncome(household *Household, federal *Federal, state *State) float64 {
+	return household.grossIncome() - calcCombinedTax(household, federal, state)
+}
+
+// solveGross returns the smallest gross, to the dollar, at which `net(gross)` reaches `target`.
+// net isn't continuous or even monotonic with phase-outs and cliffs, so rather than assume a
+// single root it scans upward for the first crossing, then bisects within it.
+func solveGross(target float64, net func(gross float64) float64) float64 {
+	step := math.Max(1000, target/200)
+	lo := 0.0
+	if net(lo) >= target {
+		return lo
+	}
+	hi := lo + step
+	for net(hi) < target {
+		lo, hi = hi, hi+step
+		if hi > 100*target+1e6 {
+			// taxes never exceed income, so this means `net` is broken
+			return math.NaN()
+		}
+	}
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if net(mid) >= target {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	// the crossing is within a dollar above `lo`, so it's the next whole dollar or the one after
+	if gross := math.Ceil(lo); gross < hi && net(gross) >= target {
+		return gross
+	}
+	return math.Ceil(hi)
+}
+
+func printGrossUps(household *Household, grossUps []*GrossUp, target float64, bonus bool) {
+	label := "Income"
+	if bonus {
+		label = "Bonus"
+		fmt.Printf("\nBonus needed on top of $%.0f to net $%.0f\n", household.income, target)
+	} else {
+		fmt.Printf("\nIncome needed to net $%.0f\n", target)
+	}
+	fmt.Printf("    State                %-10s Tax        Tax rate\n", label)
+	fmt.Println("=======================================================")
+	for i, grossUp := range grossUps {
+		fmt.Printf("%-3d %-20s $%-9.0f $%-9.0f %.3f%%\n", i+1, grossUp.state.name,
+			grossUp.gross, grossUp.tax, 100*effectiveRate(grossUp.tax, grossUp.gross))
+	}
+	fmt.Println("=======================================================")
+}
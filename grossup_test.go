@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSolveGross(t *testing.T) {
+	tests := []struct {
+		name   string
+		target float64
+		net    func(gross float64) float64
+		want   float64
+	}{
+		{"flat tax", 70000, func(gross float64) float64 { return 0.7 * gross }, 100000},
+		{"flat tax, between dollars", 70000.35, func(gross float64) float64 { return 0.7 * gross }, 100001},
+		{"already netted", 5000, func(gross float64) float64 { return 6000 + 0.7*gross }, 0},
+		{"before a cliff", 39000, cliff, 48750},
+		// the cliff leaves no income netting $40,001 until $56,252
+		{"past a cliff", 40001, cliff, 56252},
+		{"large target", 5e6, func(gross float64) float64 { return 0.55 * gross }, 9090910},
+	}
+	for _, test := range tests {
+		if got := solveGross(test.target, test.net); got != test.want {
+			t.Errorf("%s: solveGross(%.2f) = %.0f, want %.0f", test.name, test.target, got, test.want)
+		}
+	}
+}
+
+// cliff nets 80% of gross, but loses a $5,000 benefit at $50,000
+func cliff(gross float64) float64 {
+	if gross >= 50000 {
+		return 0.8*gross - 5000
+	}
+	return 0.8 * gross
+}
+
+func TestSolveGrossNeverReached(t *testing.T) {
+	for _, net := range []func(float64) float64{
+		func(gross float64) float64 { return 0 },
+		func(gross float64) float64 { return -gross },
+		func(gross float64) float64 { return math.Min(gross, 50000) },
+	} {
+		if got := solveGross(60000, net); !math.IsNaN(got) {
+			t.Errorf("solveGross() = %.0f, want NaN", got)
+		}
+	}
+}
+
+func TestCalcGrossUpNetsTarget(t *testing.T) {
+	household := &Household{dividends: 5000, mfj: true, numDependents: 2}
+	federal := initializeFederal(household)
+	for _, state := range initializeStates(household, federal) {
+		for _, bonus := range []bool{false, true} {
+			scenario := *household
+			if bonus {
+				scenario.income = 80000
+			}
+			grossUp := calcGrossUp(&scenario, federal, state, 100000, bonus)
+			base := 0.0
+			if bonus {
+				base = calcNetIncome(&scenario, federal, state)
+			}
+			scenario.income += grossUp.gross
+			if net := calcNetIncome(&scenario, federal, state) - base; net < 100000 || net > 100000+1 {
+				t.Errorf("%s (bonus %t): grossing up to %.0f nets %.2f, want 100000", state.abbrev, bonus, grossUp.gross, net)
+			}
+		}
+	}
+}
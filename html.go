@@ -0,0 +1,200 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// HTMLReport is everything the HTML report shows. the page has no external references, so it
+// can be emailed or attached as a single file.
+type HTMLReport struct {
+	Report  *Report
+	Inputs  []HTMLField
+	Federal []HTMLField
+	Rows    []HTMLRow
+	Chart   template.HTML
+}
+
+type HTMLField struct {
+	Name  string
+	Value string
+}
+
+type HTMLRow struct {
+	StateResult
+	Marginal float64
+	Lines    []WorksheetLine
+}
+
+func writeHTML(w io.Writer, household *Household, federal *Federal, states *[51]*State,
+	sweep *Sweep, ascending bool, options *PlotOptions) {
+	report := newReport(household, federal, states)
+	series := newRateSeries(sweep, states, options)
+	width, height := plotSize(series)
+	title := plotTitle(ascending, sweep, states, options)
+	chart := renderSVG(width, height, func(c Canvas) {
+		drawRateChart(c, width, height, title, series, options)
+	})
+
+	f := report.Federal.Components
+	page := &HTMLReport{
+		Report: report,
+		Inputs: htmlInputs(report.Inputs),
+		Federal: []HTMLField{
+			{"Gross income", formatMoney(f.GrossIncome)},
+			{"Ordinary income", formatMoney(f.OrdinaryIncome)},
+			{"Deductions", formatMoney(f.Deductions)},
+			{"Taxable ordinary income", formatMoney(f.TaxableIncome)},
+			{"Capital gains and qualified dividends", formatMoney(f.CapitalGains)},
+			{"Income tax", formatMoney(f.IncomeTax)},
+			{"Capital gains tax", formatMoney(f.CapitalGainsTax)},
+			{"Payroll tax", formatMoney(f.PayrollTax)},
+			{"Earned income tax credit", formatMoney(-f.EITC)},
+			{"Total tax", formatMoney(f.Tax)},
+		},
+		// the SVG is drawn here, and its text is escaped as it's drawn
+		Chart: template.HTML(chart),
+	}
+	for i, state := range states {
+		worksheet := &Worksheet{}
+		state.calcBreakdown(household, federal, worksheet)
+		page.Rows = append(page.Rows, HTMLRow{
+			StateResult: report.States[i],
+			Marginal:    calcStateMarginalRate(household, federal, state),
+			Lines:       worksheet.Lines,
+		})
+	}
+	if err := htmlTemplate.Execute(w, page); err != nil {
+		panic(err)
+	}
+}
+
+// htmlInputs lists the inputs that were given, by flag name. income is always listed.
+func htmlInputs(inputs HouseholdInput) []HTMLField {
+	var fields []HTMLField
+	v := reflect.ValueOf(inputs)
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if value.IsZero() && field.Name != "Income" {
+			continue
+		}
+		s := ""
+		switch value.Kind() {
+		case reflect.Float64:
+			s = formatMoney(value.Float())
+		case reflect.Bool:
+			s = "yes"
+		default:
+			s = strconv.FormatInt(value.Int(), 10)
+		}
+		fields = append(fields, HTMLField{"-" + field.Tag.Get("json"), s})
+	}
+	return fields
+}
+
+// formatMoney formats dollars with thousands separators and cents, e.g. $-1,234.50
+func formatMoney(amount float64) string {
+	s := strconv.FormatFloat(math.Abs(amount), 'f', 2, 64)
+	whole, cents := s[:len(s)-3], s[len(s)-3:]
+	var b strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if amount < 0 && s != "0.00" {
+		return "$-" + b.String() + cents
+	}
+	return "$" + b.String() + cents
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"money":   formatMoney,
+	"percent": func(rate float64) string { return strconv.FormatFloat(100*rate, 'f', 3, 64) + "%" },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>taxify: state income tax report for {{money .Report.Inputs.Income}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 1000px; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { padding: 0.25em 0.75em; text-align: right; border-bottom: 1px solid #ddd; }
+th:first-child, td:first-child, .name { text-align: left; }
+#states th { cursor: pointer; user-select: none; }
+#states th[data-order="asc"]::after { content: " \25B2"; }
+#states th[data-order="desc"]::after { content: " \25BC"; }
+details { margin: 0.25em 0; }
+summary { cursor: pointer; }
+svg { max-width: 100%; height: auto; }
+.note { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>State income tax report: 50 states and DC</h1>
+<p class="note">Tax year {{.Report.TaxYear}}, calculated by taxify. Effective rates are tax over gross income;
+marginal rates are the state tax on the next $1,000 of wages.</p>
+
+<h2>Inputs</h2>
+<table>
+{{range .Inputs}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+
+<h2>Federal</h2>
+<table>
+{{range .Federal}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}<tr><td>Effective rate</td><td>{{percent .Report.Federal.EffectiveRate}}</td></tr>
+</table>
+
+<h2>States</h2>
+<p class="note">Click a column to sort by it, and a state to see how its tax was calculated.</p>
+<table id="states">
+<thead><tr><th>Rank</th><th class="name">State</th><th>Tax</th><th>Effective rate</th><th>Marginal rate</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr><td data-value="{{.Rank}}">{{.Rank}}</td><td class="name" data-value="{{.Name}}"><a href="#explain-{{.Abbrev}}">{{.Name}}</a></td><td data-value="{{.Components.Tax}}">{{money .Components.Tax}}</td><td data-value="{{.EffectiveRate}}">{{percent .EffectiveRate}}</td><td data-value="{{.Marginal}}">{{percent .Marginal}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Effective rate by income</h2>
+{{.Chart}}
+
+<h2>How each state's tax was calculated</h2>
+{{range .Rows}}<details id="explain-{{.Abbrev}}">
+<summary>{{.Rank}}. {{.Name}}: {{money .Components.Tax}}, {{percent .EffectiveRate}}</summary>
+<table>
+<tr><th>Step</th><th>Amount</th><th>Taxable income</th><th>Tax</th></tr>
+{{range .Lines}}<tr><td>{{.Step}}</td><td>{{money .Amount}}</td><td>{{money .TaxableIncome}}</td><td>{{money .Tax}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}
+<script>
+document.querySelectorAll("#states th").forEach(function (th, column) {
+  th.addEventListener("click", function () {
+    var tbody = document.querySelector("#states tbody");
+    var rows = Array.prototype.slice.call(tbody.rows);
+    var ascending = th.dataset.order !== "asc";
+    document.querySelectorAll("#states th").forEach(function (other) { delete other.dataset.order; });
+    th.dataset.order = ascending ? "asc" : "desc";
+    rows.sort(function (a, b) {
+      var x = a.cells[column].dataset.value, y = b.cells[column].dataset.value;
+      var order = isNaN(x) || isNaN(y) ? x.localeCompare(y) : x - y;
+      return ascending ? order : -order;
+    });
+    rows.forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+function openExplanation() {
+  var target = document.getElementById(location.hash.slice(1));
+  if (target && target.tagName === "DETAILS") { target.open = true; }
+}
+window.addEventListener("hashchange", openExplanation);
+openExplanation();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHTMLSortsOnTheTaxShown(t *testing.T) {
+	household := &Household{income: 87654.32}
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	sortByEffectiveRate(states)
+	var out bytes.Buffer
+	writeHTML(&out, household, federal, states, newSweep(household, 10, federal, states), false,
+		&PlotOptions{top: 5})
+	page := out.String()
+	if strings.Contains(page, "50-state") {
+		t.Errorf("the report calls itself 50-state, but it lists %d jurisdictions", len(states))
+	}
+
+	// the tax cells come after each state's name
+	cell := regexp.MustCompile(`</a></td><td data-value="([^"]*)">\$([0-9,.-]*)</td>`)
+	matches := cell.FindAllStringSubmatch(page, -1)
+	if len(matches) != len(states) {
+		t.Fatalf("found %d tax cells, want %d", len(matches), len(states))
+	}
+	for _, match := range matches {
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			t.Fatalf("tax data-value %q isn't a number", match[1])
+		}
+		shown, _ := strconv.ParseFloat(strings.ReplaceAll(match[2], ",", ""), 64)
+		if !within(value, shown) {
+			t.Errorf("tax cell sorts on %s but shows $%s", match[1], match[2])
+		}
+	}
+}
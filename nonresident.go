@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// hasReciprocity returns whether the state exempts wages earned by residents of `abbrev`.
+func (state *State) hasReciprocity(abbrev string) bool {
+	for _, other := range state.reciprocity {
+		if strings.EqualFold(other, abbrev) {
+			return true
+		}
+	}
+	return false
+}
+
+// calcWorkShare returns the share of wages the work state taxes a resident of the home state
+// on, given the share of days worked remotely from the home state.
+func calcWorkShare(home, work *State, remoteShare float64) float64 {
+	if home == work || work.exemptsNonresidents || work.hasReciprocity(home.abbrev) {
+		return 0
+	}
+	if work.appliesConvenienceRule(home) {
+		// remote days are assumed to be for the employee's convenience, which doesn't change the source
+		return 1
+	}
+	return 1 - math.Max(0, math.Min(1, remoteShare))
+}
+
+// appliesConvenienceRule returns whether the state sources remote days worked by a resident of
+// `home` to itself. a retaliatory rule only applies to residents of states with a rule of their own.
+func (state *State) appliesConvenienceRule(home *State) bool {
+	return state.convenienceRule && (!state.retaliatoryRule || home.convenienceRule)
+}
+
+// wagesOnly returns a copy of the household without investment or retirement income,
+// which is only ever sourced to the home state.
+func (household *Household) wagesOnly() *Household {
+	wages := *household
+	wages.capitalGains, wages.dividends, wages.ordinaryInterest = 0, 0, 0
+	wages.socialSecurity, wages.pension, wages.militaryPension, wages.iraDistributions = 0, 0, 0, 0
+	wages.treasuryInterest, wages.inStateMuniInterest, wages.otherMuniInterest = 0, 0, 0
+	return &wages
+}
+
+// calcOtherStateCredit returns the home state's credit for `otherTax` paid to another state on
+// `otherIncome`. it's limited to the home state's tax on that same share of its AGI.
+func calcOtherStateCredit(homeTax, otherTax int, otherIncome, totalIncome float64) int {
+	if totalIncome <= 0 {
+		return 0
+	}
+	limit := math.Max(0, float64(homeTax)*otherIncome/totalIncome)
+	return int(math.Min(float64(otherTax), limit))
+}
+
+func printCommute(household *Household, federal *Federal, states *[51]*State,
+	homeAbbrev, workAbbrev string, remoteShare float64) {
+	home, work := findState(states, homeAbbrev), findState(states, workAbbrev)
+	if home == nil || work == nil {
+		usageError("-home and -work must both be state abbreviations, got %q and %q", homeAbbrev, workAbbrev)
+	}
+	workShare := calcWorkShare(home, work, remoteShare)
+	sourced := household.wagesOnly().scaled(workShare)
+	workTax := 0
+	if home != work {
+		workTax = work.calcSourcedTax(household, sourced, federal)
+	}
+	homeTax, _ := home.calcIncomeTax(household, federal)
+	grossIncome := household.grossIncome()
+	credit := calcOtherStateCredit(homeTax, workTax, home.calcAGI(sourced, federal), home.calcAGI(household, federal))
+
+	fmt.Printf("\nCommuter report for income of $%.0f, living in %s and working in %s\n",
+		household.income, home.abbrev, work.abbrev)
+	switch {
+	case work.exemptsNonresidents:
+		fmt.Printf("%s doesn't tax nonresidents' wages\n", work.name)
+	case work.hasReciprocity(home.abbrev):
+		fmt.Printf("%s and %s have a reciprocity agreement: wages are only taxed by %s\n",
+			work.name, home.name, home.name)
+	case work.appliesConvenienceRule(home) && remoteShare > 0:
+		fmt.Printf("%s's convenience of the employer rule sources remote days to %s\n", work.name, work.name)
+	}
+	fmt.Println("    Item                             Income     Tax")
+	fmt.Println("=======================================================")
+	fmt.Printf("*   %-32s $%-9.0f $%d\n", federal.name, grossIncome, federal.incomeTax)
+	fmt.Println("=======================================================")
+	fmt.Printf("    %-32s $%-9.0f $%d\n", work.name+" (nonresident)", sourced.grossIncome(), workTax)
+	fmt.Printf("    %-32s $%-9.0f $%d\n", home.name+" (resident)", grossIncome, homeTax)
+	fmt.Printf("    %-32s %-10s $%d\n", "  credit for taxes paid to "+work.abbrev, "", -credit)
+	fmt.Println("=======================================================")
+	stateTax := workTax + homeTax - credit
+	fmt.Printf("    %-32s %-10s $%d (%.3f%%)\n", "Total state", "", stateTax, 100*float64(stateTax)/grossIncome)
+	fmt.Printf("    %-32s %-10s $%d (%.3f%%)\n", "Total", "", stateTax+federal.incomeTax,
+		100*float64(stateTax+federal.incomeTax)/grossIncome)
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestCalcWorkShare(t *testing.T) {
+	states := testStates()
+	tests := []struct {
+		home, work  string
+		remoteShare float64
+		want        float64
+	}{
+		{"NJ", "NJ", 0, 0},
+		{"NJ", "NY", 0.4, 1},   // NY's convenience rule
+		{"NJ", "PA", 0.4, 0},   // reciprocity
+		{"NJ", "CT", 0.4, 0.6}, // CT's rule only applies to residents of states with one
+		{"NY", "CT", 0.4, 1},
+		{"TX", "AR", 0.25, 0.75}, // AR repealed its rule
+		{"TX", "DE", 0.25, 1},
+		{"TX", "MA", 1.5, 0},
+	}
+	for _, test := range tests {
+		home, work := findState(states, test.home), findState(states, test.work)
+		if got := calcWorkShare(home, work, test.remoteShare); !within(got, test.want) {
+			t.Errorf("calcWorkShare(%s, %s, %g) = %g, want %g", test.home, test.work, test.remoteShare, got, test.want)
+		}
+	}
+}
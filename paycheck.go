@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+)
+
+var payPeriods = map[string]int{
+	"weekly":      52,
+	"biweekly":    26,
+	"semimonthly": 24,
+	"monthly":     12,
+}
+
+// the additional medicare tax is withheld on each job's wages over this, regardless of filing status
+const additionalMedicareWithholding = 200000
+
+type W4 struct {
+	// Form W-4 (2020 and later)
+	multipleJobs     bool    // step 2 checkbox
+	dependents       float64 // step 3, the annual credit amount
+	otherIncome      float64 // step 4(a)
+	deductions       float64 // step 4(b)
+	extraWithholding float64 // step 4(c), per pay period
+}
+
+type WithholdingFormula struct {
+	// a state's percentage method, used instead of the annual liability formula when any field
+	// is set. wages are annualized, reduced by the standard deduction, personal allowances and
+	// allowances from the state's withholding certificate, then run through the brackets.
+	standardDeduction int     // replaces the filing status' standard deduction
+	personalAllowance int     // per filer, replaces the filing status' personal exemption
+	allowance         int     // per allowance claimed on the certificate
+	allowanceCredit   float64 // credited per filer and per allowance, replacing exemption credits
+	rateMultiplier    float64 // the withholding rates are the income tax rates times this
+}
+
+type Paycheck struct {
+	periods        int
+	gross          float64
+	pretax         float64 // 401(k), HSA and FSA contributions
+	federal        float64 // income tax withholding
+	socialSecurity float64
+	medicare       float64
+	state          float64
+}
+
+func runPaycheck(args []string) {
+	fs := flag.NewFlagSet("paycheck", flag.ExitOnError)
+	newHousehold := householdFlags(fs)
+	abbrev := fs.String("state", "", "State of residence, e.g. CA (required)")
+	frequency := fs.String("frequency", "biweekly", "Pay frequency: weekly, biweekly, semimonthly or monthly")
+	multipleJobs := fs.Bool("multipleJobs", false, "W-4 step 2: multiple jobs or spouse works")
+	w4Dependents := fs.Float64("w4Dependents", 0, "W-4 step 3: claim dependent and other credits amount")
+	otherIncome := fs.Float64("otherIncome", 0, "W-4 step 4(a): other income")
+	w4Deductions := fs.Float64("w4Deductions", 0, "W-4 step 4(b): deductions")
+	extraWithholding := fs.Float64("extraWithholding", 0, "W-4 step 4(c): extra withholding per pay period")
+	allowances := fs.Int("allowances", -1, "Allowances on the state withholding certificate beyond the filers' own (default the number of dependents)")
+	fs.Parse(args)
+
+	periods, ok := payPeriods[*frequency]
+	if !ok {
+		usageError("paycheck: -frequency must be weekly, biweekly, semimonthly or monthly, got %q", *frequency)
+	}
+	household := newHousehold()
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	state := findState(states, *abbrev)
+	if state == nil {
+		usageError("paycheck: -state must be a state abbreviation, got %q", *abbrev)
+	}
+	if *allowances < 0 {
+		*allowances = household.numDependents
+	}
+	w4 := &W4{
+		multipleJobs:     *multipleJobs,
+		dependents:       *w4Dependents,
+		otherIncome:      *otherIncome,
+		deductions:       *w4Deductions,
+		extraWithholding: *extraWithholding,
+	}
+	paycheck := calcPaycheck(household, federal, state, w4, *allowances, periods)
+	printPaycheck(household, federal, state, paycheck, *frequency)
+}
+
+func calcPaycheck(household *Household, federal *Federal, state *State, w4 *W4, allowances, periods int) *Paycheck {
+	n := float64(periods)
+	payrollWages := household.payrollWages()
+	socialSecurity := federal.calcSocialSecurityTax(payrollWages)
+	medicare := federal.calcMedicareTax(payrollWages, additionalMedicareWithholding)
+	return &Paycheck{
+		periods:        periods,
+		gross:          household.income / n,
+		pretax:         household.pretaxContributions() / n,
+		federal:        federal.calcAnnualWithholding(household, w4)/n + w4.extraWithholding,
+		socialSecurity: socialSecurity / n,
+		medicare:       medicare / n,
+		state:          state.calcAnnualWithholding(household, allowances) / n,
+	}
+}
+
+// calcAnnualWithholding returns the annual federal income tax withholding under the
+// Publication 15-T percentage method for automated payroll systems.
+func (federal *Federal) calcAnnualWithholding(household *Household, w4 *W4) float64 {
+	data := federal.single
+	if household.mfj {
+		data = federal.couple
+	}
+	// the annual tables are the income tax brackets offset by the standard deduction
+	adjustedWages := household.wages() + w4.otherIncome - w4.deductions - float64(data.standardDeduction)
+	tax := 0.0
+	if w4.multipleJobs {
+		// the step 2 checkbox tables halve both the brackets and the standard deduction
+		adjustedWages = 2 * (adjustedWages + float64(data.standardDeduction)/2)
+		tax = taxEngine(&adjustedWages, &data.incomeBrackets, &data.incomeRates) / 2
+	} else {
+		tax = taxEngine(&adjustedWages, &data.incomeBrackets, &data.incomeRates)
+	}
+	return math.Max(0, tax-w4.dependents)
+}
+
+// calcAnnualWithholding returns the state's annual income tax withholding on wages alone.
+// states without their own percentage method withhold their annual liability on the wages.
+func (state *State) calcAnnualWithholding(household *Household, allowances int) float64 {
+	wagesOnly := household.wagesOnly()
+	wagesOnly.numDependents = allowances
+	formula := &state.withholding
+	if *formula == (WithholdingFormula{}) {
+		tax, _ := state.calcIncomeTax(wagesOnly, initializeFederal(wagesOnly))
+		return math.Max(0, float64(tax))
+	}
+
+	data := state.single
+	if household.mfj {
+		data = state.couple
+	}
+	numFilers := float64(household.numFilers())
+	deduction := float64(data.standardDeduction)
+	if formula.standardDeduction > 0 {
+		deduction = float64(formula.standardDeduction)
+	}
+	personal := float64(data.personalExemption)
+	if formula.personalAllowance > 0 {
+		personal = float64(formula.personalAllowance) * numFilers
+	} else if state.exemptionIsCredit {
+		personal = 0
+	}
+	taxable := state.calcWages(household) - deduction - personal - float64(allowances*formula.allowance)
+	tax := taxEngine(&taxable, &data.brackets, &data.rates)
+	if formula.rateMultiplier > 0 {
+		tax *= formula.rateMultiplier
+	}
+	if formula.allowanceCredit > 0 {
+		tax -= formula.allowanceCredit * (numFilers + float64(allowances))
+	} else if state.exemptionIsCredit && formula.personalAllowance == 0 {
+		tax -= float64(data.personalExemption)
+	}
+	return math.Max(0, tax)
+}
+
+func printPaycheck(household *Household, federal *Federal, state *State, paycheck *Paycheck, frequency string) {
+	n := float64(paycheck.periods)
+	net := paycheck.gross - paycheck.pretax - paycheck.federal - paycheck.socialSecurity -
+		paycheck.medicare - paycheck.state
+	fmt.Printf("\n%s paycheck for wages of $%.0f in %s\n", frequency, household.income, state.name)
+	fmt.Println("    Item                        Per period   Annual")
+	fmt.Println("==================================================")
+	for _, row := range []struct {
+		name   string
+		amount float64
+	}{
+		{"Gross pay", paycheck.gross},
+		{"less pre-tax contributions", paycheck.pretax},
+		{"less federal withholding", paycheck.federal},
+		{"less social security", paycheck.socialSecurity},
+		{"less medicare", paycheck.medicare},
+		{"less " + state.abbrev + " withholding", paycheck.state},
+	} {
+		fmt.Printf("    %-27s $%-11.2f $%.0f\n", row.name, row.amount, row.amount*n)
+	}
+	fmt.Println("==================================================")
+	fmt.Printf("    %-27s $%-11.2f $%.0f\n", "Net pay", net, net*n)
+	fmt.Println("==================================================")
+
+	// compare the year's withholding with the annual liability, positive means a refund
+	federalTax := float64(federal.incomeTax) - federal.calcPayrollTax(household)
+	fmt.Println("Year end          Withheld   Liability  Over/(under) withheld")
+	fmt.Printf("%-17s $%-9.0f $%-9.0f $%.0f\n", federal.name, paycheck.federal*n, federalTax,
+		paycheck.federal*n-federalTax)
+	fmt.Printf("%-17s $%-9.0f $%-9d $%.0f\n", state.abbrev, paycheck.state*n, state.incomeTax,
+		paycheck.state*n-float64(state.incomeTax))
+}
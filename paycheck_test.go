@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// the expected amounts are from Publication 15-T (2022) Worksheet 1A and the annual percentage
+// method tables for automated payroll systems, with a 2020 or later Form W-4
+func TestFederalWithholdingPub15T(t *testing.T) {
+	tests := []struct {
+		name      string
+		household Household
+		w4        W4
+		want      float64
+	}{
+		// $60,000 - $8,600 = $51,400: $4,807.50 + 22% over $46,125
+		{"single", Household{income: 60000}, W4{}, 5968},
+		// $100,000 - $12,900 = $87,100: $2,055 + 12% over $33,550
+		{"married filing jointly", Household{income: 100000, mfj: true}, W4{}, 8481},
+		{"step 3 credits", Household{income: 60000}, W4{dependents: 2000}, 3968},
+		{"step 3 credits over the tax", Household{income: 30000}, W4{dependents: 4000}, 0},
+		// $60,000 + $10,000 - $4,000 - $8,600 = $57,400: $4,807.50 + 22% over $46,125
+		{"step 4(a) and 4(b)", Household{income: 60000}, W4{otherIncome: 10000, deductions: 4000}, 7288},
+		// the step 2 checkbox table: $7,606.75 + 24% over $51,013
+		{"single, step 2", Household{income: 60000}, W4{multipleJobs: true}, 9763.63},
+		// $4,807.50 + 22% over $54,725
+		{"married filing jointly, step 2", Household{income: 100000, mfj: true}, W4{multipleJobs: true}, 14768},
+		{"401(k) deferrals aren't taxed", Household{income: 65000, contributions401k: 5000}, W4{}, 5968},
+		{"under the standard deduction", Household{income: 8000}, W4{}, 0},
+	}
+	for _, test := range tests {
+		federal := initializeFederal(&test.household)
+		// the checkbox tables' brackets are rounded to the dollar
+		if got := federal.calcAnnualWithholding(&test.household, &test.w4); math.Abs(got-test.want) > 0.5 {
+			t.Errorf("%s: calcAnnualWithholding() = %.2f, want %.2f", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPaycheckFICA(t *testing.T) {
+	household := &Household{income: 260000, contributions401k: 20000, contributionsHSA: 3000}
+	federal := initializeFederal(household)
+	state := findState(initializeStates(household, federal), "TX")
+	paycheck := calcPaycheck(household, federal, state, &W4{}, 0, 26)
+	// 401(k) deferrals are subject to FICA, HSA contributions through a cafeteria plan aren't
+	wantSocialSecurity := 147000 * 0.062 / 26
+	wantMedicare := (257000*0.0145 + 57000*0.009) / 26
+	if !within(paycheck.socialSecurity, wantSocialSecurity) || !within(paycheck.medicare, wantMedicare) {
+		t.Errorf("social security and medicare = %.2f and %.2f, want %.2f and %.2f",
+			paycheck.socialSecurity, paycheck.medicare, wantSocialSecurity, wantMedicare)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// a categorical palette that stays distinguishable for the usual handful of curves.
+// past 10 curves the colors repeat, alternating with dashed lines.
+var palette = []color.RGBA{
+	{31, 119, 180, 255}, {255, 127, 14, 255}, {44, 160, 44, 255}, {214, 39, 40, 255}, {148, 103, 189, 255},
+	{140, 86, 75, 255}, {227, 119, 194, 255}, {127, 127, 127, 255}, {188, 189, 34, 255}, {23, 190, 207, 255},
+}
+
+type PlotOptions struct {
+	top     int  // the number of states to plot, in ranked order
+	federal bool // include the federal curve
+	xMax    float64
+	yMin    float64 // yMin and yMax are both 0 for an automatic range
+	yMax    float64
+}
+
+// Series is one curve: effective rate (y) by income (x)
+type Series struct {
+	label  string
+	color  color.RGBA
+	dashed bool
+	points [][2]float64
+}
+
+// newRateSeries turns a sweep into curves for the top states, in the order they're ranked
+func newRateSeries(sweep *Sweep, states *[51]*State, options *PlotOptions) []*Series {
+	top := options.top
+	if top <= 0 || top > len(states) {
+		top = len(states)
+	}
+	var series []*Series
+	if options.federal {
+		federal := &Series{label: "Federal", color: black, dashed: true}
+		for _, point := range sweep.Points {
+			federal.points = append(federal.points, [2]float64{point.Income, point.Federal.EffectiveRate})
+		}
+		series = append(series, federal)
+	}
+	for i, state := range states[:top] {
+		s := &Series{
+			label:  fmt.Sprintf("%d %s", i+1, state.name),
+			color:  palette[i%len(palette)],
+			dashed: (i/len(palette))%2 == 1,
+		}
+		for _, point := range sweep.Points {
+			s.points = append(s.points, [2]float64{point.Income, point.States[state.abbrev].EffectiveRate})
+		}
+		series = append(series, s)
+	}
+	return series
+}
+
+// plotSize returns the size of a rate chart, tall enough for the legend
+func plotSize(series []*Series) (int, int) {
+	return 900, int(math.Max(560, float64(90+16*len(series))))
+}
+
+// drawRateChart draws the curves with axes, gridlines and a legend on the right
+func drawRateChart(c Canvas, width, height int, title string, series []*Series, options *PlotOptions) {
+	left, right, top, bottom := 70.0, float64(width)-200, 64.0, float64(height)-50
+
+	xMax := options.xMax
+	yMin, yMax := options.yMin, options.yMax
+	autoY := yMin == 0 && yMax == 0
+	for _, s := range series {
+		for _, p := range s.points {
+			if options.xMax == 0 {
+				xMax = math.Max(xMax, p[0])
+			}
+			if autoY && p[0] <= xMax {
+				yMin, yMax = math.Min(yMin, p[1]), math.Max(yMax, p[1])
+			}
+		}
+	}
+	_, xMax, xStep := niceTicks(0, xMax, 8)
+	yMin, yMax, yStep := niceTicks(yMin, yMax, 8)
+	toX := func(x float64) float64 { return left + (right-left)*x/xMax }
+	toY := func(y float64) float64 {
+		y = math.Max(yMin, math.Min(yMax, y)) // clip to the axes
+		return bottom - (bottom-top)*(y-yMin)/(yMax-yMin)
+	}
+
+	c.rect(0, 0, float64(width), float64(height), white)
+	c.text(float64(width)/2, 26, title, 16, black, anchorMiddle)
+	for x := 0.0; x <= xMax+xStep/2; x += xStep {
+		c.line(toX(x), top, toX(x), bottom, lightGray, 1, false)
+		c.text(toX(x), bottom+18, formatDollars(x), 11, gray, anchorMiddle)
+	}
+	for y := yMin; y <= yMax+yStep/2; y += yStep {
+		c.line(left, toY(y), right, toY(y), lightGray, 1, false)
+		c.text(left-8, toY(y)+4, formatPercent(y, yStep), 11, gray, anchorEnd)
+	}
+	c.line(left, bottom, right, bottom, black, 1, false)
+	c.line(left, top, left, bottom, black, 1, false)
+	if yMin < 0 {
+		c.line(left, toY(0), right, toY(0), gray, 1, false)
+	}
+	c.text((left+right)/2, bottom+40, "Income", 12, black, anchorMiddle)
+	c.text(left-8, top-14, "Effective rate", 12, black, anchorStart)
+
+	for i, s := range series {
+		var points [][2]float64
+		for _, p := range s.points {
+			if p[0] <= xMax {
+				points = append(points, [2]float64{toX(p[0]), toY(p[1])})
+			}
+		}
+		c.polyline(points, s.color, 2, s.dashed)
+
+		y := top + 8 + 16*float64(i)
+		c.line(right+16, y-4, right+40, y-4, s.color, 2, s.dashed)
+		c.text(right+46, y, s.label, 11, black, anchorStart)
+	}
+}
+
+// niceTicks widens lo-hi to multiples of a 1, 2, 2.5 or 5 step giving about n ticks
+func niceTicks(lo, hi float64, n int) (float64, float64, float64) {
+	if hi <= lo {
+		hi = lo + 1
+	}
+	raw := (hi - lo) / float64(n)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := 10 * magnitude
+	for _, m := range []float64{1, 2, 2.5, 5} {
+		if m*magnitude >= raw {
+			step = m * magnitude
+			break
+		}
+	}
+	return math.Floor(lo/step+1e-9) * step, math.Ceil(hi/step-1e-9) * step, step
+}
+
+// formatDollars abbreviates amounts for axis labels, e.g. $250k and $1.5M
+func formatDollars(amount float64) string {
+	trim := func(v float64) string { return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64) }
+	switch {
+	case math.Abs(amount) >= 1e6:
+		return "$" + trim(amount/1e6) + "M"
+	case math.Abs(amount) >= 1e3:
+		return "$" + trim(amount/1e3) + "k"
+	}
+	return "$" + trim(amount)
+}
+
+// formatPercent formats a rate with as many decimals as the tick step needs
+func formatPercent(rate, step float64) string {
+	decimals := int(math.Max(0, math.Ceil(-math.Log10(100*step)-1e-9)))
+	s := strconv.FormatFloat(100*rate, 'f', decimals, 64)
+	if strings.Trim(s, "-0.") == "" {
+		s = "0"
+	}
+	return s + "%"
+}
+
+// writePlot renders the effective rate curves of the sweep to an .svg or .png file
+func writePlot(filename string, ascending bool, sweep *Sweep, states *[51]*State, options *PlotOptions) {
+	series := newRateSeries(sweep, states, options)
+	title := plotTitle(ascending, sweep, states, options)
+	width, height := plotSize(series)
+	writeCanvas(filename, width, height, func(c Canvas) {
+		drawRateChart(c, width, height, title, series, options)
+	})
+}
+
+func plotTitle(ascending bool, sweep *Sweep, states *[51]*State, options *PlotOptions) string {
+	if options.top <= 0 || options.top >= len(states) {
+		return fmt.Sprintf("Effective tax rate by income, ranked at $%.0f", sweep.Inputs.Income)
+	}
+	rank := "highest"
+	if ascending {
+		rank = "lowest"
+	}
+	return fmt.Sprintf("Effective tax rate by income, the %d %s-taxed states at $%.0f", options.top, rank, sweep.Inputs.Income)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// calcSourcedTax returns the state's tax for a part-year resident or nonresident on the
+// `sourced` part of the household's income, following the state's part-year method. income
+// is prorated by the state's AGI, so untaxed benefits and exempt interest don't count.
+func (state *State) calcSourcedTax(household, sourced *Household, federal *Federal) int {
+	sourcedAGI := state.calcAGI(sourced, federal)
+	if sourcedAGI <= 0 {
+		return 0
+	}
+	switch state.partYearMethod {
+	case sourceOnly:
+		tax, _ := state.calcIncomeTax(sourced, federal)
+		return tax
+	default:
+		tax, _ := state.calcIncomeTax(household, federal)
+		return int(float64(tax) * math.Min(1, sourcedAGI/state.calcAGI(household, federal)))
+	}
+}
+
+// residencyShare returns the share of the year spent in the old state before moving on `moveDate`.
+// income is assumed to be received evenly over the year.
+func residencyShare(moveDate time.Time) float64 {
+	startOfYear := time.Date(moveDate.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	daysInYear := startOfYear.AddDate(1, 0, 0).Sub(startOfYear).Hours() / 24
+	return moveDate.Sub(startOfYear).Hours() / 24 / daysInYear
+}
+
+// findState returns the state with the given abbreviation, or nil.
+func findState(states *[51]*State, abbrev string) *State {
+	for _, state := range states {
+		if strings.EqualFold(state.abbrev, abbrev) {
+			return state
+		}
+	}
+	return nil
+}
+
+// parseRelocation looks up the -from and -to states and parses the -move date
+func parseRelocation(states *[51]*State, fromAbbrev, toAbbrev, moveDate string) (*State, *State, time.Time, error) {
+	from, to := findState(states, fromAbbrev), findState(states, toAbbrev)
+	if from == nil || to == nil {
+		return nil, nil, time.Time{}, fmt.Errorf("-from and -to must both be state abbreviations, got %q and %q",
+			fromAbbrev, toAbbrev)
+	}
+	if from == to {
+		return nil, nil, time.Time{}, fmt.Errorf("-from and -to must be different states, got %s for both", from.abbrev)
+	}
+	date, err := time.Parse("2006-01-02", moveDate)
+	if err != nil {
+		return nil, nil, time.Time{}, fmt.Errorf("-move must be a date as YYYY-MM-DD, got %q", moveDate)
+	}
+	if date.Year() != taxYear {
+		return nil, nil, time.Time{}, fmt.Errorf("-move must be a date in %d, the tax year, got %q", taxYear, moveDate)
+	}
+	return from, to, date, nil
+}
+
+// calcRelocation returns the share of the year spent in `from` and each state's part-year tax
+func calcRelocation(household *Household, federal *Federal, from, to *State, date time.Time) (float64, int, int) {
+	fromShare := residencyShare(date)
+	fromTax := from.calcSourcedTax(household, household.scaled(fromShare), federal)
+	toTax := to.calcSourcedTax(household, household.scaled(1-fromShare), federal)
+	return fromShare, fromTax, toTax
+}
+
+func printRelocation(household *Household, federal *Federal, states *[51]*State,
+	fromAbbrev, toAbbrev, moveDate string) {
+	from, to, date, err := parseRelocation(states, fromAbbrev, toAbbrev, moveDate)
+	if err != nil {
+		usageError("%v", err)
+	}
+	fromShare, fromTax, toTax := calcRelocation(household, federal, from, to, date)
+	grossIncome := household.grossIncome()
+
+	fmt.Printf("\nRelocation report for income of $%.0f, moving %s -> %s on %s\n",
+		household.income, from.abbrev, to.abbrev, date.Format("Jan 2, 2006"))
+	fmt.Println("    State                Resident  Income     Tax       Effective Rate")
+	fmt.Println("=====================================================================")
+	fmt.Printf("*   %-20s %-9s $%-9.0f $%-8d %.3f%%\n",
+		federal.name, "", grossIncome, federal.incomeTax, 100*federal.effectiveRate)
+	fmt.Println("=====================================================================")
+	for _, row := range []struct {
+		state *State
+		share float64
+		tax   int
+	}{{from, fromShare, fromTax}, {to, 1 - fromShare, toTax}} {
+		fmt.Printf("    %-20s %-9s $%-9.0f $%-8d %.3f%%\n", row.state.name,
+			fmt.Sprintf("%.1f%%", 100*row.share), grossIncome*row.share, row.tax, 100*float64(row.tax)/grossIncome)
+	}
+	fmt.Println("=====================================================================")
+	stateTax := fromTax + toTax
+	fmt.Printf("    %-20s %-9s $%-9.0f $%-8d %.3f%%\n", "Total state", "", grossIncome,
+		stateTax, 100*float64(stateTax)/grossIncome)
+	fmt.Printf("    %-20s %-9s $%-9.0f $%-8d %.3f%%\n", "Total", "", grossIncome,
+		stateTax+federal.incomeTax, 100*float64(stateTax+federal.incomeTax)/grossIncome)
+}
+
+// usageError reports invalid command line input and exits.
+func usageError(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalcRelocation(t *testing.T) {
+	household := &Household{income: 120000}
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	// July 2 is 182 days into 2022
+	midyear := time.Date(2022, time.July, 2, 0, 0, 0, 0, time.UTC)
+	share := 182.0 / 365
+	fullYear := func(abbrev string, h *Household) int {
+		tax, _ := findState(states, abbrev).calcIncomeTax(h, federal)
+		return tax
+	}
+	tests := []struct {
+		name     string
+		from, to string
+		date     time.Time
+		wantFrom int
+		wantTo   int
+	}{
+		// CA taxes the whole year's income at its rates, then prorates the tax
+		{"CA prorates its full-year tax", "CA", "TX", midyear,
+			int(float64(fullYear("CA", household)) * share), 0},
+		// NJ taxes only the income received while resident, so it's in lower brackets
+		{"NJ taxes the sourced income", "NJ", "TX", midyear,
+			fullYear("NJ", household.scaled(share)), 0},
+		{"both states' methods", "CA", "NJ", midyear,
+			int(float64(fullYear("CA", household)) * share), fullYear("NJ", household.scaled(1-share))},
+		{"moving on January 1 is a full year in the new state", "NJ", "CA",
+			time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC), 0, fullYear("CA", household)},
+	}
+	for _, test := range tests {
+		fromShare, fromTax, toTax := calcRelocation(household, federal, findState(states, test.from),
+			findState(states, test.to), test.date)
+		if fromTax != test.wantFrom || toTax != test.wantTo {
+			t.Errorf("%s: %s tax = %d and %s tax = %d, want %d and %d", test.name,
+				test.from, fromTax, test.to, toTax, test.wantFrom, test.wantTo)
+		}
+		if test.date == midyear && !within(fromShare, share) {
+			t.Errorf("%s: the share of the year in %s = %.4f, want %.4f", test.name, test.from, fromShare, share)
+		}
+	}
+
+	// proration charges the full-year rates, so it's more than the tax on the sourced income alone
+	_, prorated, _ := calcRelocation(household, federal, findState(states, "CA"), findState(states, "TX"), midyear)
+	if sourced := fullYear("CA", household.scaled(share)); prorated <= sourced {
+		t.Errorf("CA prorated tax = %d, want more than %d on the sourced income", prorated, sourced)
+	}
+}
+
+func TestParseRelocation(t *testing.T) {
+	states := testStates()
+	tests := []struct {
+		name     string
+		from, to string
+		move     string
+		valid    bool
+	}{
+		{"valid", "ny", "FL", "2022-06-15", true},
+		{"the same state", "NY", "ny", "2022-06-15", false},
+		{"unknown state", "NY", "XX", "2022-06-15", false},
+		{"not a date", "NY", "FL", "June 15", false},
+		{"another year", "NY", "FL", "2023-06-15", false},
+	}
+	for _, test := range tests {
+		_, _, _, err := parseRelocation(states, test.from, test.to, test.move)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: parseRelocation(%q, %q, %q) = %v, want valid %t",
+				test.name, test.from, test.to, test.move, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// bump reportSchemaVersion whenever a field of the JSON report is renamed, removed or changes
+// meaning. adding fields doesn't need a new version.
+const reportSchemaVersion = 1
+
+// Report is the JSON form of the 50-state report. field names are part of the schema.
+type Report struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	TaxYear       int            `json:"taxYear"`
+	Inputs        HouseholdInput `json:"inputs"`
+	Federal       FederalResult  `json:"federal"`
+	States        []StateResult  `json:"states"`
+}
+
+// HouseholdInput mirrors the household flags, using the flag names as keys
+type HouseholdInput struct {
+	Income              float64 `json:"income"`
+	CapitalGains        float64 `json:"cg"`
+	Dividends           float64 `json:"interest"`
+	Qualified           bool    `json:"qualified"`
+	Joint               bool    `json:"joint"`
+	Dependents          int     `json:"dependents"`
+	Age                 int     `json:"age"`
+	SpouseAge           int     `json:"spouseAge"`
+	Blind               bool    `json:"blind"`
+	SpouseBlind         bool    `json:"spouseBlind"`
+	SocialSecurity      float64 `json:"ss"`
+	Pension             float64 `json:"pension"`
+	MilitaryPension     float64 `json:"military"`
+	IRADistributions    float64 `json:"ira"`
+	Contributions401k   float64 `json:"401k"`
+	ContributionsHSA    float64 `json:"hsa"`
+	ContributionsFSA    float64 `json:"fsa"`
+	Contributions529    float64 `json:"529"`
+	TreasuryInterest    float64 `json:"treasury"`
+	InStateMuniInterest float64 `json:"munis"`
+	OtherMuniInterest   float64 `json:"otherMunis"`
+	SpouseIncome        float64 `json:"spouseIncome"`
+}
+
+// Sweep is the JSON form of the CSV output: the household scaled to `steps` evenly spaced
+// fractions of itself
+type Sweep struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	TaxYear       int            `json:"taxYear"`
+	Inputs        HouseholdInput `json:"inputs"`
+	Points        []SweepPoint   `json:"points"`
+}
+
+type SweepPoint struct {
+	Income  float64               `json:"income"`
+	Federal TaxAndRate            `json:"federal"`
+	States  map[string]TaxAndRate `json:"states"` // keyed by abbreviation
+}
+
+type TaxAndRate struct {
+	Tax           int     `json:"tax"`
+	EffectiveRate float64 `json:"effectiveRate"`
+}
+
+type FederalResult struct {
+	Name          string            `json:"name"`
+	Tax           int               `json:"tax"`
+	EffectiveRate float64           `json:"effectiveRate"`
+	Components    FederalComponents `json:"components"`
+}
+
+type FederalComponents struct {
+	GrossIncome     float64 `json:"grossIncome"`
+	OrdinaryIncome  float64 `json:"ordinaryIncome"`
+	Deductions      float64 `json:"deductions"`
+	TaxableIncome   float64 `json:"taxableIncome"`
+	CapitalGains    float64 `json:"capitalGains"`
+	IncomeTax       float64 `json:"incomeTax"`
+	CapitalGainsTax float64 `json:"capitalGainsTax"`
+	PayrollTax      float64 `json:"payrollTax"`
+	EITC            float64 `json:"eitc"`
+	Tax             float64 `json:"tax"`
+}
+
+type StateResult struct {
+	Rank          int             `json:"rank"`
+	Name          string          `json:"name"`
+	Abbrev        string          `json:"abbrev"`
+	Tax           int             `json:"tax"`
+	EffectiveRate float64         `json:"effectiveRate"`
+	Components    StateComponents `json:"components"`
+}
+
+// StateComponents is a StateBreakdown. deductions and exemptions that a state gives as
+// credits are listed at their full amount and are also counted in Credits.
+type StateComponents struct {
+	GrossIncome         float64 `json:"grossIncome"`
+	Income              float64 `json:"income"`
+	RetirementIncome    float64 `json:"retirementIncome"`
+	CapitalGains        float64 `json:"capitalGains"`
+	Dividends           float64 `json:"dividends"`
+	DependentExemption  float64 `json:"dependentExemption"`
+	StandardDeduction   float64 `json:"standardDeduction"`
+	Plan529             float64 `json:"plan529"`
+	PersonalExemption   float64 `json:"personalExemption"`
+	FederalTaxDeduction float64 `json:"federalTaxDeduction"`
+	TaxableIncome       float64 `json:"taxableIncome"`
+	BracketTax          float64 `json:"bracketTax"`
+	SpecialRateTax      float64 `json:"specialRateTax"`
+	Recapture           float64 `json:"recapture"`
+	Credits             float64 `json:"credits"`
+	EITC                float64 `json:"eitc"`
+	Tax                 float64 `json:"tax"`
+}
+
+func newHouseholdInput(household *Household) HouseholdInput {
+	return HouseholdInput{
+		Income:              household.income,
+		CapitalGains:        household.capitalGains,
+		Dividends:           household.dividends,
+		Qualified:           household.qualified,
+		Joint:               household.mfj,
+		Dependents:          household.numDependents,
+		Age:                 household.age,
+		SpouseAge:           household.spouseAge,
+		Blind:               household.blind,
+		SpouseBlind:         household.spouseBlind,
+		SocialSecurity:      household.socialSecurity,
+		Pension:             household.pension,
+		MilitaryPension:     household.militaryPension,
+		IRADistributions:    household.iraDistributions,
+		Contributions401k:   household.contributions401k,
+		ContributionsHSA:    household.contributionsHSA,
+		ContributionsFSA:    household.contributionsFSA,
+		Contributions529:    household.contributions529,
+		TreasuryInterest:    household.treasuryInterest,
+		InStateMuniInterest: household.inStateMuniInterest,
+		OtherMuniInterest:   household.otherMuniInterest,
+		SpouseIncome:        household.spouseIncome,
+	}
+}
+
+func (in *HouseholdInput) household() *Household {
+	return &Household{
+		income:              in.Income,
+		capitalGains:        in.CapitalGains,
+		dividends:           in.Dividends,
+		qualified:           in.Qualified,
+		mfj:                 in.Joint,
+		numDependents:       in.Dependents,
+		age:                 in.Age,
+		spouseAge:           in.SpouseAge,
+		blind:               in.Blind,
+		spouseBlind:         in.SpouseBlind,
+		socialSecurity:      in.SocialSecurity,
+		pension:             in.Pension,
+		militaryPension:     in.MilitaryPension,
+		iraDistributions:    in.IRADistributions,
+		contributions401k:   in.Contributions401k,
+		contributionsHSA:    in.ContributionsHSA,
+		contributionsFSA:    in.ContributionsFSA,
+		contributions529:    in.Contributions529,
+		treasuryInterest:    in.TreasuryInterest,
+		inStateMuniInterest: in.InStateMuniInterest,
+		otherMuniInterest:   in.OtherMuniInterest,
+		spouseIncome:        in.SpouseIncome,
+	}
+}
+
+// maxAmount bounds every input amount, far above any household's, so that no total or tax
+// overflows to infinity, which JSON can't encode
+const maxAmount = 1e12
+
+// validate rejects inputs the flags would accept but the calculation doesn't handle
+func (in *HouseholdInput) validate() error {
+	amounts := []struct {
+		name  string
+		value float64
+	}{
+		{"income", in.Income}, {"cg", in.CapitalGains}, {"interest", in.Dividends},
+		{"ss", in.SocialSecurity}, {"pension", in.Pension}, {"military", in.MilitaryPension},
+		{"ira", in.IRADistributions}, {"401k", in.Contributions401k}, {"hsa", in.ContributionsHSA},
+		{"fsa", in.ContributionsFSA}, {"529", in.Contributions529}, {"treasury", in.TreasuryInterest},
+		{"munis", in.InStateMuniInterest}, {"otherMunis", in.OtherMuniInterest},
+		{"spouseIncome", in.SpouseIncome},
+	}
+	for _, amount := range amounts {
+		if amount.value < 0 {
+			return fmt.Errorf("%s must not be negative, got %g", amount.name, amount.value)
+		}
+		// this also rejects NaN and infinity
+		if !(amount.value <= maxAmount) {
+			return fmt.Errorf("%s must be at most %g, got %g", amount.name, float64(maxAmount), amount.value)
+		}
+	}
+	if in.Contributions401k+in.ContributionsHSA+in.ContributionsFSA > in.Income {
+		return fmt.Errorf("401k, hsa and fsa contributions come out of income and can't exceed it")
+	}
+	if in.SpouseIncome > 0 && (!in.Joint || in.SpouseIncome > in.Income) {
+		return fmt.Errorf("spouseIncome is the part of income the spouse earned, so it needs joint and can't exceed income")
+	}
+	if in.Dependents < 0 {
+		return fmt.Errorf("dependents must not be negative, got %d", in.Dependents)
+	}
+	if in.Age < 0 || in.Age > 130 || in.SpouseAge < 0 || in.SpouseAge > 130 {
+		return fmt.Errorf("age and spouseAge must be between 0 and 130")
+	}
+	return nil
+}
+
+func newFederalResult(household *Household, federal *Federal) FederalResult {
+	b := federal.calcBreakdown(household)
+	return FederalResult{
+		Name:          federal.name,
+		Tax:           int(b.tax),
+		EffectiveRate: effectiveRate(b.tax, b.grossIncome),
+		Components: FederalComponents{
+			GrossIncome:     b.grossIncome,
+			OrdinaryIncome:  b.ordinaryIncome,
+			Deductions:      b.deductions,
+			TaxableIncome:   b.taxableIncome,
+			CapitalGains:    b.capitalGains,
+			IncomeTax:       b.incomeTax,
+			CapitalGainsTax: b.capitalGainsTax,
+			PayrollTax:      b.payrollTax,
+			EITC:            b.eitc,
+			Tax:             b.tax,
+		},
+	}
+}
+
+func newStateResult(household *Household, federal *Federal, state *State, rank int) StateResult {
+	b := state.calcBreakdown(household, federal, nil)
+	return StateResult{
+		Rank:          rank,
+		Name:          state.name,
+		Abbrev:        state.abbrev,
+		Tax:           int(b.tax),
+		EffectiveRate: effectiveRate(b.tax, b.grossIncome),
+		Components: StateComponents{
+			GrossIncome:         b.grossIncome,
+			Income:              b.income,
+			RetirementIncome:    b.retirementIncome,
+			CapitalGains:        b.capitalGains,
+			Dividends:           b.dividends,
+			DependentExemption:  b.dependentExemption,
+			StandardDeduction:   b.standardDeduction,
+			Plan529:             b.plan529,
+			PersonalExemption:   b.personalExemption,
+			FederalTaxDeduction: b.federalTaxDeduction,
+			TaxableIncome:       b.taxableIncome,
+			BracketTax:          b.bracketTax,
+			SpecialRateTax:      b.specialRateTax,
+			Recapture:           b.recapture,
+			Credits:             b.credits,
+			EITC:                b.eitc,
+			Tax:                 b.tax,
+		},
+	}
+}
+
+// sortByEffectiveRate ranks the states from the highest effective rate to the lowest
+func sortByEffectiveRate(states *[51]*State) {
+	sort.SliceStable(states[:], func(i, j int) bool {
+		return states[i].effectiveRate > states[j].effectiveRate
+	})
+}
+
+// newReport builds the report for the states in their current (ranked) order
+func newReport(household *Household, federal *Federal, states *[51]*State) *Report {
+	report := &Report{
+		SchemaVersion: reportSchemaVersion,
+		TaxYear:       taxYear,
+		Inputs:        newHouseholdInput(household),
+		Federal:       newFederalResult(household, federal),
+		States:        make([]StateResult, 0, len(states)),
+	}
+	for i, state := range states {
+		report.States = append(report.States, newStateResult(household, federal, state, i+1))
+	}
+	return report
+}
+
+// newSweep calculates the household scaled down to each of `numSteps` fractions of it, the
+// same rows writeToCSV writes
+func newSweep(household *Household, numSteps int, federal *Federal, states *[51]*State) *Sweep {
+	sweep := &Sweep{
+		SchemaVersion: reportSchemaVersion,
+		TaxYear:       taxYear,
+		Inputs:        newHouseholdInput(household),
+		Points:        make([]SweepPoint, 0, numSteps),
+	}
+	for i := 0; i < numSteps; i++ {
+		row := *household.sweepStep(i, numSteps)
+		federalTax := federal.calcBreakdown(&row)
+		point := SweepPoint{
+			Income:  row.income,
+			Federal: TaxAndRate{int(federalTax.tax), effectiveRate(federalTax.tax, federalTax.grossIncome)},
+			States:  make(map[string]TaxAndRate, len(states)),
+		}
+		for _, state := range states {
+			stateTax := state.calcBreakdown(&row, federal, nil)
+			point.States[state.abbrev] = TaxAndRate{int(stateTax.tax), effectiveRate(stateTax.tax, stateTax.grossIncome)}
+		}
+		sweep.Points = append(sweep.Points, point)
+	}
+	return sweep
+}
+
+// effectiveRate is tax / grossIncome, but 0 rather than NaN with no income, which JSON can't encode
+func effectiveRate(tax, grossIncome float64) float64 {
+	if grossIncome == 0 {
+		return 0
+	}
+	return tax / grossIncome
+}
+
+func writeJSON(w io.Writer, v interface{}) {
+	if err := encodeJSON(w, v); err != nil {
+		panic(err)
+	}
+}
+
+// encodeJSON writes `v` as indented JSON. it fails on values JSON can't encode, like infinity.
+func encodeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
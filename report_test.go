@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSweepScalesTheHousehold(t *testing.T) {
+	household := &Household{income: 50000, contributions401k: 20000, contributionsHSA: 5000, pension: 10000,
+		capitalGains: 4000, dividends: 2000}
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	sweep := newSweep(household, 10, federal, states)
+	for i, point := range sweep.Points {
+		row := household.sweepStep(i, 10)
+		input := newHouseholdInput(row)
+		if err := input.validate(); err != nil {
+			t.Errorf("step %d: %v", i+1, err)
+		}
+		if !within(point.Income, 5000*float64(i+1)) || !within(row.contributions401k, 2000*float64(i+1)) ||
+			!within(row.pension, 1000*float64(i+1)) {
+			t.Errorf("step %d: income %.2f, 401(k) %.2f and pension %.2f, want a tenth of the household's each step",
+				i+1, point.Income, row.contributions401k, row.pension)
+		}
+		ca := findState(states, "CA")
+		if got, want := point.States["CA"].Tax, int(ca.calcBreakdown(row, federal, nil).tax); got != want {
+			t.Errorf("step %d: CA tax %d, want %d", i+1, got, want)
+		}
+	}
+	if last := household.sweepStep(9, 10); *last != *household {
+		t.Errorf("the last step is %+v, want the household", *last)
+	}
+}
+
+func TestValidateBounds(t *testing.T) {
+	tests := []struct {
+		name  string
+		input HouseholdInput
+		valid bool
+	}{
+		{"at the bound", HouseholdInput{Income: maxAmount}, true},
+		{"over it", HouseholdInput{Income: 1e308, CapitalGains: 1e308}, false},
+		{"a huge pension", HouseholdInput{Income: 1000, Pension: 2e12}, false},
+		{"negative", HouseholdInput{Dividends: -1}, false},
+		{"not a number", HouseholdInput{Income: math.NaN()}, false},
+		{"infinite", HouseholdInput{Income: 1000, TreasuryInterest: math.Inf(1)}, false},
+		{"a spouse's share", HouseholdInput{Income: 150000, Joint: true, SpouseIncome: 50000}, true},
+		{"a spouse on a single return", HouseholdInput{Income: 150000, SpouseIncome: 50000}, false},
+		{"a spouse earning more than the household", HouseholdInput{Income: 50000, Joint: true, SpouseIncome: 60000},
+			false},
+	}
+	for _, test := range tests {
+		if err := test.input.validate(); (err == nil) != test.valid {
+			t.Errorf("%s: validate() = %v, want valid %t", test.name, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	maxRequestBytes = 1 << 20
+	maxSweepSteps   = 1000
+	// slow clients can't hold connections open: the headers, the body and the whole exchange
+	// each have to finish within these, and idle keep-alive connections are closed
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 30 * time.Second
+	writeTimeout      = 60 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+// CalculateRequest is the body of every endpoint: the household flags, plus `state` for
+// /v1/state and `steps` for /v1/sweep
+type CalculateRequest struct {
+	HouseholdInput
+	State string `json:"state,omitempty"`
+	Steps int    `json:"steps,omitempty"`
+}
+
+// StateReport is the response of /v1/state: one state, ranked among all 51
+type StateReport struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	TaxYear       int            `json:"taxYear"`
+	Inputs        HouseholdInput `json:"inputs"`
+	Federal       FederalResult  `json:"federal"`
+	State         StateResult    `json:"state"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	fs.Parse(args)
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           newServeMux(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	log.Printf("taxify listening on %s", *addr)
+	log.Fatal(server.ListenAndServe())
+}
+
+// newServeMux routes the API's endpoints to their handlers
+func newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/state", handleState)
+	mux.HandleFunc("/v1/states", handleStates)
+	mux.HandleFunc("/v1/sweep", handleSweep)
+	return mux
+}
+
+func handleState(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	household := req.household()
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	sortByEffectiveRate(states)
+	for i, state := range states {
+		if strings.EqualFold(state.abbrev, req.State) {
+			respond(w, http.StatusOK, &StateReport{
+				SchemaVersion: reportSchemaVersion,
+				TaxYear:       taxYear,
+				Inputs:        newHouseholdInput(household),
+				Federal:       newFederalResult(household, federal),
+				State:         newStateResult(household, federal, state, i+1),
+			})
+			return
+		}
+	}
+	respondError(w, http.StatusBadRequest, "state must be a state abbreviation, got %q", req.State)
+}
+
+func handleStates(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	household := req.household()
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	sortByEffectiveRate(states)
+	respond(w, http.StatusOK, newReport(household, federal, states))
+}
+
+func handleSweep(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Steps == 0 {
+		req.Steps = 100
+	}
+	if req.Steps < 1 || req.Steps > maxSweepSteps {
+		respondError(w, http.StatusBadRequest, "steps must be between 1 and %d, got %d", maxSweepSteps, req.Steps)
+		return
+	}
+	household := req.household()
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	respond(w, http.StatusOK, newSweep(household, req.Steps, federal, states))
+}
+
+// decodeRequest reads and validates the body of a POST, writing the error response if it
+// isn't valid
+func decodeRequest(w http.ResponseWriter, r *http.Request) (*CalculateRequest, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		respondError(w, http.StatusMethodNotAllowed, "%s needs a POST with a JSON body", r.URL.Path)
+		return nil, false
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	req := &CalculateRequest{}
+	if err := dec.Decode(req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(w, http.StatusRequestEntityTooLarge, "the body is over %d bytes", maxRequestBytes)
+		} else {
+			respondError(w, http.StatusBadRequest, "invalid JSON body: %v", err)
+		}
+		return nil, false
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		respondError(w, http.StatusBadRequest, "the body must be a single JSON object")
+		return nil, false
+	}
+	if err := req.validate(); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, "%v", err)
+		return nil, false
+	}
+	return req, true
+}
+
+func respond(w http.ResponseWriter, status int, v interface{}) {
+	var body bytes.Buffer
+	if err := encodeJSON(&body, v); err != nil {
+		// validate bounds the inputs so that this doesn't happen, but a client still gets JSON
+		log.Printf("encoding a %d response: %v", status, err)
+		status = http.StatusInternalServerError
+		body.Reset()
+		writeJSON(&body, &errorResponse{fmt.Sprintf("the result can't be encoded as JSON: %v", err)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body.Bytes())
+}
+
+func respondError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	respond(w, status, &errorResponse{fmt.Sprintf(format, args...)})
+}
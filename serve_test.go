@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeStatusCodes(t *testing.T) {
+	server := httptest.NewServer(newServeMux())
+	defer server.Close()
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"POST", "/v1/states", `{"income": 150000, "joint": true, "dependents": 2}`, http.StatusOK},
+		{"POST", "/v1/state", `{"income": 150000, "state": "ny"}`, http.StatusOK},
+		{"POST", "/v1/sweep", `{"income": 150000, "steps": 10}`, http.StatusOK},
+		{"POST", "/v1/sweep", `{"income": 150000}`, http.StatusOK},
+		{"GET", "/v1/states", ``, http.StatusMethodNotAllowed},
+		{"PUT", "/v1/sweep", `{"income": 150000}`, http.StatusMethodNotAllowed},
+		{"POST", "/v1/states", `{"income": 150000`, http.StatusBadRequest},
+		{"POST", "/v1/states", `{"income": "lots"}`, http.StatusBadRequest},
+		{"POST", "/v1/states", `{"salary": 150000}`, http.StatusBadRequest},
+		{"POST", "/v1/states", `{"income": 1} {"income": 2}`, http.StatusBadRequest},
+		{"POST", "/v1/state", `{"income": 150000, "state": "XX"}`, http.StatusBadRequest},
+		{"POST", "/v1/sweep", `{"income": 150000, "steps": 1001}`, http.StatusBadRequest},
+		{"POST", "/v1/sweep", `{"income": 150000, "steps": -1}`, http.StatusBadRequest},
+		{"POST", "/v1/states", `{"income": -1}`, http.StatusUnprocessableEntity},
+		{"POST", "/v1/states", `{"income": 1000, "401k": 2000}`, http.StatusUnprocessableEntity},
+		// these overflow to infinity, which JSON can't encode
+		{"POST", "/v1/states", `{"income": 1e308, "cg": 1e308}`, http.StatusUnprocessableEntity},
+		{"POST", "/v1/state", `{"income": 1e308, "cg": 1e308, "state": "CA"}`, http.StatusUnprocessableEntity},
+		{"POST", "/v1/sweep", `{"income": 1e13}`, http.StatusUnprocessableEntity},
+		{"POST", "/v1/states", `{"income": 1e12, "cg": 1e12, "interest": 1e12}`, http.StatusOK},
+		{"POST", "/v1/state", `{"state": "` + strings.Repeat("x", maxRequestBytes) + `"}`, http.StatusRequestEntityTooLarge},
+		{"POST", "/v1/nothing", `{}`, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, server.URL+test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.want {
+			t.Errorf("%s %s %.40s: status %d, want %d", test.method, test.path, test.body, resp.StatusCode, test.want)
+		}
+		if test.want == http.StatusMethodNotAllowed && resp.Header.Get("Allow") != http.MethodPost {
+			t.Errorf("%s %s: Allow = %q, want POST", test.method, test.path, resp.Header.Get("Allow"))
+		}
+	}
+}
+
+func TestServeErrorBody(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newServeMux().ServeHTTP(rec, httptest.NewRequest("POST", "/v1/states", strings.NewReader(`{"income": -1}`)))
+	var body errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == "" {
+		t.Errorf("error body = %q (%v), want {\"error\": ...}", rec.Body.String(), err)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+}
+
+func TestRespondEncodingError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	respond(rec, http.StatusOK, &TaxAndRate{Tax: 1, EffectiveRate: math.Inf(1)})
+	var body errorResponse
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == "" {
+		t.Errorf("error body = %q (%v), want {\"error\": ...}", rec.Body.String(), err)
+	}
+}
@@ -13,15 +13,84 @@ import (
 	"fmt"
 	"math"
 	"os"
-	"sort"
 	"strconv"
+	"strings"
 )
 
+// the tax year the brackets, deductions and credits below are for
+const taxYear = 2022
+
+type Household struct {
+	income        float64 // ordinary (earned) income
+	capitalGains  float64
+	dividends     float64 // dividends and (taxable) interest
+	qualified     bool    // are the dividends qualified?
+	mfj           bool
+	numDependents int
+	age           int
+	spouseAge     int // ignored unless mfj
+	blind         bool
+	spouseBlind   bool
+	// retirement income
+	socialSecurity   float64 // total benefits received
+	pension          float64
+	militaryPension  float64
+	iraDistributions float64 // traditional IRA and 401(k) distributions
+	// contributions, all but 529 contributions come out of `income` pre-tax
+	contributions401k float64
+	contributionsHSA  float64 // made through a cafeteria plan
+	contributionsFSA  float64
+	contributions529  float64
+	// interest with special treatment
+	treasuryInterest    float64 // exempt from state tax
+	inStateMuniInterest float64 // from bonds issued by the state of residence, whichever state that is
+	otherMuniInterest   float64 // from bonds issued by other states
+	ordinaryInterest    float64 // always taxed as ordinary income, even when `dividends` are qualified
+	spouseIncome        float64 // the part of `income` the spouse earned, for payroll taxes
+}
+
 type FilingStatus struct {
-	brackets          []int
-	rates             []float64
-	standardDeduction int
-	personalExemption int
+	brackets            []int
+	rates               []float64
+	standardDeduction   int
+	additionalDeduction int // per filer who is 65+ and again if blind
+	personalExemption   int
+	socialSecurityLimit int // AGI at or below which social security benefits aren't taxed; 0 means no limit
+	plan529Limit        int // 529 contributions deductible (or eligible for the credit); -1 means unlimited
+	deductionPhaseout   Phaseout
+	exemptionPhaseout   Phaseout
+	recaptures          []Recapture
+}
+
+type RecaptureKind int
+
+const (
+	// `amount` of tax is added for every `step` of AGI (or part thereof) over `start`, up to `max`
+	addBack RecaptureKind = iota
+	// tax becomes a flat top rate on all taxable income: the benefit of the lower brackets
+	// phases in over `step` dollars of AGI past `start`, and again past each higher bracket
+	flatRate
+)
+
+type Recapture struct {
+	// recaptures the benefit of the lower brackets for high-AGI filers
+	kind   RecaptureKind
+	start  int
+	step   int
+	amount float64
+	max    float64
+}
+
+type Phaseout struct {
+	// reduces a deduction or exemption as AGI rises past `start`. every `step`
+	// dollars (or part thereof) removes `reduction` dollars from the amount.
+	// a step of 0 is a continuous phase-out, i.e. `reduction` per dollar.
+	// past `end` no further reduction is applied; 0 means no end.
+	// the zero value is no phase-out.
+	start     int
+	end       int
+	step      int
+	reduction float64
 }
 
 type State struct {
@@ -29,9 +98,23 @@ type State struct {
 	abbrev               string
 	dependentExemption   int
 	dependentIsCredit    bool
+	dependentPhaseout    Phaseout // applied per dependent
 	stdDeductionIsCredit bool
 	exemptionIsCredit    bool
 	incomeTypesTaxed     []float32 // *[1] see below
+	eitc                 StateEITC
+	senior               SeniorProvisions
+	retirement           RetirementProvisions
+	pretax               PretaxProvisions
+	partYearMethod       PartYearMethod // also used for nonresidents
+	reciprocity          []string       // residents of these states aren't taxed on wages earned here
+	exemptsNonresidents  bool           // nonresidents' wages aren't taxed at all
+	convenienceRule      bool           // wages for remote work are sourced here unless it's for the employer's necessity
+	retaliatoryRule      bool           // the convenience rule only applies to residents of states that have one
+	taxesInStateMunis    bool
+	exemptsOtherMunis    bool
+	estimates            EstimatedTaxRules // the zero value uses defaultStateEstimates
+	withholding          WithholdingFormula
 	single               FilingStatus
 	couple               FilingStatus
 	effectiveRate        float64
@@ -41,6 +124,80 @@ type State struct {
 // *[1] {ordinary, capital gains, dividends/interest} *negative means special case
 // if capital gains is negative, a deduction of x is applied to capital gains before adding it to taxableIncome
 
+type SeniorProvisions struct {
+	// amounts are per filer (and spouse, if mfj) who qualifies
+	age                 int // the age provisions start at
+	exemption           int // follows `exemptionIsCredit`
+	blindExemption      int // follows `exemptionIsCredit`, at any age
+	credit              int // a non-refundable credit regardless of `exemptionIsCredit`
+	retirementExclusion int // excluded from retirement income, then investment income
+	earnedExclusion     int // the part of `retirementExclusion` that may also come from earned income
+}
+
+type PartYearMethod int
+
+const (
+	// tax is calculated as a full-year resident, then prorated by the share of income received while resident
+	prorateByIncome PartYearMethod = iota
+	// only the income received while resident is taxed, as though it were the whole year's income
+	sourceOnly
+)
+
+type RetirementProvisions struct {
+	socialSecurityTaxed    float64 // share of the federally taxable benefits the state taxes. most states exempt them.
+	pensionExclusion       int     // per qualifying filer; -1 excludes all of it
+	exclusionAge           int     // minimum age for `pensionExclusion`; 0 means any age
+	includesIRA            bool    // the exclusion also covers IRA/401(k) distributions
+	includesSocialSecurity bool    // the exclusion also covers taxable social security
+	militaryExclusion      int     // of military retirement pay; -1 excludes all of it
+}
+
+type PretaxProvisions struct {
+	// states that don't conform to the federal exclusion add the contributions back to wages
+	taxes401k         bool
+	taxesHSA          bool
+	taxesFSA          bool
+	plan529CreditRate float64 // 529 contributions earn a credit at this rate; 0 means they're deducted instead
+}
+
+type StateEITC struct {
+	// state EITCs piggyback on the federal credit as a percentage of it.
+	// rates are indexed by the number of qualifying children, the last
+	// entry applying to any higher count. nil means the state has no EITC.
+	rates      []float64
+	refundable bool
+}
+
+type EITCSchedule struct {
+	creditRate    float64 // phase-in rate on earned income
+	earnedAmount  int     // earned income at which the maximum credit is reached
+	phaseoutRate  float64
+	phaseoutStart int // AGI (or earned income, if greater) at which the credit begins to phase out
+}
+
+// StateBreakdown holds the pieces of a state's income tax calculation. each deduction and
+// exemption is listed whether it came off income or, for states that give them as credits,
+// off the tax, in which case it's also counted in `credits`.
+type StateBreakdown struct {
+	grossIncome         float64
+	income              float64 // ordinary income, after pre-tax contributions and age-based exclusions
+	retirementIncome    float64 // taxable retirement income
+	capitalGains        float64
+	dividends           float64 // including taxable muni interest
+	dependentExemption  float64
+	standardDeduction   float64
+	plan529             float64
+	personalExemption   float64
+	federalTaxDeduction float64
+	taxableIncome       float64 // income run through the brackets, which may be negative
+	bracketTax          float64
+	specialRateTax      float64 // capital gains and dividends taxed at their own rates
+	recapture           float64
+	credits             float64
+	eitc                float64
+	tax                 float64
+}
+
 type FedFilingStatus struct {
 	// if dividends are qualified, they get added to capital gains instead of income
 	incomeBrackets       []int
@@ -48,6 +205,25 @@ type FedFilingStatus struct {
 	capitalGainsBrackets []int
 	capitalGainsRates    []float64
 	standardDeduction    int
+	additionalDeduction  int            // per filer who is 65+ and again if blind
+	socialSecurityBases  []int          // provisional income thresholds for taxing 50% and 85% of benefits
+	eitc                 []EITCSchedule // indexed by number of qualifying children (0-3+)
+
+	// the additional medicare tax is owed on the filers' combined wages over this
+	additionalMedicareThreshold int
+}
+
+type FederalBreakdown struct {
+	grossIncome     float64
+	ordinaryIncome  float64
+	deductions      float64
+	taxableIncome   float64
+	capitalGains    float64 // including qualified dividends
+	incomeTax       float64
+	capitalGainsTax float64
+	payrollTax      float64
+	eitc            float64
+	tax             float64
 }
 
 type Federal struct {
@@ -55,15 +231,20 @@ type Federal struct {
 	abbrev             string
 	medicareRate       float64 // 0.0145
 	socialSecurityRate float64 // 0.062
-	socialSecurityCap  int     // $147,000
+	socialSecurityCap  int     // $147,000, per earner
+	eitcInvestmentCap  int     // investment income above this disqualifies the EITC
+	estimates          EstimatedTaxRules
 	single             FedFilingStatus
 	couple             FedFilingStatus
 	effectiveRate      float64
 	incomeTax          int
+
+	// 0.9%, owed on wages over the filing status' additionalMedicareThreshold and withheld
+	// on each job's wages over additionalMedicareWithholding. employers don't match it.
+	additionalMedicareRate float64
 }
 
-func initializeStates(income, capitalGains, dividends *float64,
-	federalTax, numDependents int, mfj bool) *[51]*State {
+func initializeStates(household *Household, federal *Federal) *[51]*State {
 	// TODO: move `exemptionIsCredit` and `stdDeductionIsCredit` out of FilingStatus since it's the same for both
 	states := [51]*State{
 		{
@@ -74,17 +255,21 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{-1.0, 1.0, 1.0},
+			retirement:           RetirementProvisions{pensionExclusion: -1, militaryExclusion: -1},
+			partYearMethod:       sourceOnly,
 			single: FilingStatus{
 				brackets:          []int{0, 500, 3000},
 				rates:             []float64{0.02, 0.03, 0.05},
 				standardDeduction: 2500,
 				personalExemption: 1500,
+				plan529Limit:      5000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 1000, 6000},
 				rates:             []float64{0.02, 0.03, 0.05},
 				standardDeduction: 7500,
 				personalExemption: 3000,
+				plan529Limit:      10000,
 			},
 		},
 		{
@@ -116,17 +301,22 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			retirement:           RetirementProvisions{pensionExclusion: 2500, militaryExclusion: -1},
 			single: FilingStatus{
-				brackets:          []int{0, 27808, 55615, 116843},
-				rates:             []float64{0.0259, 0.0334, 0.0417, 0.045},
-				standardDeduction: 12950,
-				personalExemption: 0,
+				brackets:            []int{0, 27808, 55615, 116843},
+				rates:               []float64{0.0259, 0.0334, 0.0417, 0.045},
+				standardDeduction:   12950,
+				additionalDeduction: 1750,
+				personalExemption:   0,
+				plan529Limit:        2000,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 55615, 111229, 333684},
-				rates:             []float64{0.0259, 0.0334, 0.0417, 0.045},
-				standardDeduction: 25900,
-				personalExemption: 0,
+				brackets:            []int{0, 55615, 111229, 333684},
+				rates:               []float64{0.0259, 0.0334, 0.0417, 0.045},
+				standardDeduction:   25900,
+				additionalDeduction: 1400,
+				personalExemption:   0,
+				plan529Limit:        4000,
 			},
 		},
 		{
@@ -137,17 +327,21 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    true,
 			incomeTypesTaxed:     []float32{1.0, -0.5, 1.0}, // only 50% of capital gains are taxed
+			senior:               SeniorProvisions{age: 65, exemption: 29, blindExemption: 29},
+			retirement:           RetirementProvisions{pensionExclusion: 6000, includesIRA: true, militaryExclusion: -1},
 			single: FilingStatus{
 				brackets:          []int{0, 4300, 8500},
 				rates:             []float64{0.02, 0.04, 0.055},
 				standardDeduction: 2200,
 				personalExemption: 29,
+				plan529Limit:      5000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 4300, 8500},
 				rates:             []float64{0.02, 0.04, 0.055},
 				standardDeduction: 4400,
 				personalExemption: 58,
+				plan529Limit:      10000,
 			},
 		},
 		{
@@ -158,6 +352,21 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    true,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			senior:               SeniorProvisions{age: 65, exemption: 129, blindExemption: 129},
+			pretax:               PretaxProvisions{taxesHSA: true},
+			estimates: EstimatedTaxRules{
+				currentYearShare:  0.9,
+				priorYearShare:    1.0,
+				highIncomeShare:   1.1,
+				highIncomeAGI:     150000,
+				priorYearAGILimit: 1000000,
+				installments:      []float64{0.30, 0.40, 0, 0.30},
+				minimumOwed:       500,
+				penaltyRate:       0.05,
+				// Form 5805 applies withholding in the installments' percentages
+				withholdingByInstallment: true,
+			},
+			withholding: WithholdingFormula{rateMultiplier: 1.1, allowanceCredit: 144.10},
 			single: FilingStatus{
 				brackets:          []int{0, 9325, 22107, 34892, 48435, 61214, 312686, 375221, 625369, 1000000},
 				rates:             []float64{0.01, 0.02, 0.04, 0.06, 0.08, 0.093, 0.103, 0.113, 0.123, 0.133},
@@ -179,17 +388,23 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.20}, refundable: true},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1, pensionExclusion: 24000, exclusionAge: 65, includesIRA: true, includesSocialSecurity: true},
 			single: FilingStatus{
-				brackets:          []int{0},
-				rates:             []float64{0.0455},
-				standardDeduction: 12950,
-				personalExemption: 0,
+				brackets:            []int{0},
+				rates:               []float64{0.0455},
+				standardDeduction:   12950,
+				additionalDeduction: 1750,
+				personalExemption:   0,
+				plan529Limit:        20700,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0},
-				rates:             []float64{0.0455},
-				standardDeduction: 25900,
-				personalExemption: 0,
+				brackets:            []int{0},
+				rates:               []float64{0.0455},
+				standardDeduction:   25900,
+				additionalDeduction: 1400,
+				personalExemption:   0,
+				plan529Limit:        31000,
 			},
 		},
 		{
@@ -200,17 +415,46 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 0.07, 1.0}, // flat rate of 7% on capital gains
+			eitc:                 StateEITC{rates: []float64{0.415}, refundable: true},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1, militaryExclusion: -1},
+			convenienceRule:      true,
+			retaliatoryRule:      true,
+			estimates: EstimatedTaxRules{
+				currentYearShare: 0.9,
+				priorYearShare:   1.0,
+				highIncomeShare:  1.0,
+				highIncomeAGI:    150000,
+				installments:     []float64{0.25, 0.25, 0.25, 0.25},
+				minimumOwed:      1000,
+				penaltyRate:      0.05,
+			},
 			single: FilingStatus{
-				brackets:          []int{0, 10000, 50000, 100000, 200000, 250000, 500000},
-				rates:             []float64{0.03, 0.05, 0.055, 0.06, 0.065, 0.069, 0.0699},
-				standardDeduction: 0,
-				personalExemption: 15000,
+				brackets:            []int{0, 10000, 50000, 100000, 200000, 250000, 500000},
+				rates:               []float64{0.03, 0.05, 0.055, 0.06, 0.065, 0.069, 0.0699},
+				standardDeduction:   0,
+				personalExemption:   15000,
+				socialSecurityLimit: 75000,
+				exemptionPhaseout:   Phaseout{start: 30000, end: 45000, step: 1000, reduction: 1000},
+				plan529Limit:        5000,
+				recaptures: []Recapture{
+					{kind: addBack, start: 56500, step: 5000, amount: 20, max: 200},   // 3% bracket phase-out
+					{kind: addBack, start: 200000, step: 5000, amount: 75, max: 2250}, // benefit recapture
+					{kind: addBack, start: 500000, step: 5000, amount: 80, max: 950},
+				},
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 20000, 100000, 200000, 400000, 500000, 1000000},
-				rates:             []float64{0.03, 0.05, 0.055, 0.06, 0.065, 0.069, 0.0699},
-				standardDeduction: 0,
-				personalExemption: 24000,
+				brackets:            []int{0, 20000, 100000, 200000, 400000, 500000, 1000000},
+				rates:               []float64{0.03, 0.05, 0.055, 0.06, 0.065, 0.069, 0.0699},
+				standardDeduction:   0,
+				personalExemption:   24000,
+				socialSecurityLimit: 100000,
+				exemptionPhaseout:   Phaseout{start: 48000, end: 96000, step: 2000, reduction: 1000},
+				plan529Limit:        10000,
+				recaptures: []Recapture{
+					{kind: addBack, start: 100500, step: 5000, amount: 40, max: 400},    // 3% bracket phase-out
+					{kind: addBack, start: 400000, step: 10000, amount: 150, max: 4500}, // benefit recapture
+					{kind: addBack, start: 1000000, step: 10000, amount: 160, max: 1900},
+				},
 			},
 		},
 		{
@@ -221,6 +465,9 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    true,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.20}, refundable: false},
+			senior:               SeniorProvisions{age: 65, exemption: 110, blindExemption: 110, retirementExclusion: 12500}, // the exclusion actually starts at 60
+			convenienceRule:      true,
 			single: FilingStatus{
 				brackets:          []int{2000, 5000, 10000, 20000, 25000, 60000},
 				rates:             []float64{0.022, 0.039, 0.048, 0.052, 0.0555, 0.066},
@@ -263,17 +510,24 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			senior:               SeniorProvisions{age: 65, retirementExclusion: 65000, earnedExclusion: 5000},
+			retirement:           RetirementProvisions{militaryExclusion: 17500},
+			withholding:          WithholdingFormula{allowance: 3000},
 			single: FilingStatus{
-				brackets:          []int{0, 750, 2250, 3750, 5250, 7000},
-				rates:             []float64{0.01, 0.02, 0.03, 0.04, 0.05, 0.0575},
-				standardDeduction: 5400,
-				personalExemption: 2700,
+				brackets:            []int{0, 750, 2250, 3750, 5250, 7000},
+				rates:               []float64{0.01, 0.02, 0.03, 0.04, 0.05, 0.0575},
+				standardDeduction:   5400,
+				additionalDeduction: 1300,
+				personalExemption:   2700,
+				plan529Limit:        4000,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 1000, 3000, 5000, 7000, 10000},
-				rates:             []float64{0.01, 0.02, 0.03, 0.04, 0.05, 0.0575},
-				standardDeduction: 7100,
-				personalExemption: 7400,
+				brackets:            []int{0, 1000, 3000, 5000, 7000, 10000},
+				rates:               []float64{0.01, 0.02, 0.03, 0.04, 0.05, 0.0575},
+				standardDeduction:   7100,
+				additionalDeduction: 1300,
+				personalExemption:   7400,
+				plan529Limit:        8000,
 			},
 		},
 		{
@@ -284,6 +538,9 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 0.0725, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.20}, refundable: true},
+			senior:               SeniorProvisions{age: 65, exemption: 1144},
+			retirement:           RetirementProvisions{pensionExclusion: -1, militaryExclusion: -1},
 			single: FilingStatus{
 				brackets:          []int{0, 2400, 4800, 9600, 14400, 19200, 24000, 36000, 48000, 150000, 175000, 200000},
 				rates:             []float64{0.014, 0.032, 0.055, 0.064, 0.068, 0.072, 0.076, 0.079, 0.0825, 0.09, 0.1, 0.11},
@@ -306,16 +563,20 @@ func initializeStates(income, capitalGains, dividends *float64,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
 			single: FilingStatus{
-				brackets:          []int{0, 1588, 4763, 7939},
-				rates:             []float64{0.01, 0.03, 0.045, 0.06},
-				standardDeduction: 12950,
-				personalExemption: 0,
+				brackets:            []int{0, 1588, 4763, 7939},
+				rates:               []float64{0.01, 0.03, 0.045, 0.06},
+				standardDeduction:   12950,
+				additionalDeduction: 1750,
+				personalExemption:   0,
+				plan529Limit:        6000,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 3176, 9526, 15878},
-				rates:             []float64{0.01, 0.03, 0.045, 0.06},
-				standardDeduction: 25900,
-				personalExemption: 0,
+				brackets:            []int{0, 3176, 9526, 15878},
+				rates:               []float64{0.01, 0.03, 0.045, 0.06},
+				standardDeduction:   25900,
+				additionalDeduction: 1400,
+				personalExemption:   0,
+				plan529Limit:        12000,
 			},
 		},
 		{
@@ -326,17 +587,26 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.18}, refundable: true},
+			senior:               SeniorProvisions{age: 65, exemption: 1000, blindExemption: 1000},
+			retirement:           RetirementProvisions{pensionExclusion: -1, includesIRA: true, militaryExclusion: -1},
+			partYearMethod:       sourceOnly,
+			reciprocity:          []string{"IA", "KY", "MI", "WI"},
+			taxesInStateMunis:    true,
+			withholding:          WithholdingFormula{personalAllowance: 2425, allowance: 1000},
 			single: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0495},
 				standardDeduction: 0,
 				personalExemption: 2375,
+				plan529Limit:      10000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0495},
 				standardDeduction: 0,
 				personalExemption: 4750,
+				plan529Limit:      20000,
 			},
 		},
 		{
@@ -347,17 +617,26 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.10}, refundable: true},
+			senior:               SeniorProvisions{age: 65, exemption: 1000, blindExemption: 1000},
+			retirement:           RetirementProvisions{militaryExclusion: -1},
+			pretax:               PretaxProvisions{plan529CreditRate: 0.20},
+			partYearMethod:       sourceOnly,
+			reciprocity:          []string{"KY", "MI", "OH", "PA", "WI"},
+			exemptsOtherMunis:    true,
 			single: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0323},
 				standardDeduction: 0,
 				personalExemption: 1000,
+				plan529Limit:      5000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0323},
 				standardDeduction: 0,
 				personalExemption: 2000,
+				plan529Limit:      5000,
 			},
 		},
 		{
@@ -368,17 +647,24 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    true,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.15}, refundable: true},
+			senior:               SeniorProvisions{age: 65, exemption: 20, blindExemption: 20},
+			retirement:           RetirementProvisions{pensionExclusion: 6000, exclusionAge: 55, includesIRA: true, militaryExclusion: -1},
+			reciprocity:          []string{"IL"},
+			taxesInStateMunis:    true,
 			single: FilingStatus{
 				brackets:          []int{0, 1743, 3486, 6972, 15687, 26145, 34860, 52290, 78435},
 				rates:             []float64{0.0033, 0.0067, 0.0225, 0.0414, 0.0563, 0.0596, 0.0625, 0.0744, 0.0853},
 				standardDeduction: 2210,
 				personalExemption: 40,
+				plan529Limit:      3522,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 1743, 3486, 6972, 15687, 26145, 34860, 52290, 78435},
 				rates:             []float64{0.0033, 0.0067, 0.0225, 0.0414, 0.0563, 0.0596, 0.0625, 0.0744, 0.0853},
 				standardDeduction: 5450,
 				personalExemption: 80,
+				plan529Limit:      7044,
 			},
 		},
 		{
@@ -389,17 +675,25 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.17}, refundable: true},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1, militaryExclusion: -1},
 			single: FilingStatus{
-				brackets:          []int{0, 15000, 30000},
-				rates:             []float64{0.031, 0.0525, 0.057},
-				standardDeduction: 3500,
-				personalExemption: 2250,
+				brackets:            []int{0, 15000, 30000},
+				rates:               []float64{0.031, 0.0525, 0.057},
+				standardDeduction:   3500,
+				additionalDeduction: 850,
+				personalExemption:   2250,
+				socialSecurityLimit: 75000,
+				plan529Limit:        3000,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 30000, 60000},
-				rates:             []float64{0.031, 0.0525, 0.057},
-				standardDeduction: 8000,
-				personalExemption: 4500,
+				brackets:            []int{0, 30000, 60000},
+				rates:               []float64{0.031, 0.0525, 0.057},
+				standardDeduction:   8000,
+				additionalDeduction: 700,
+				personalExemption:   4500,
+				socialSecurityLimit: 75000,
+				plan529Limit:        6000,
 			},
 		},
 		{
@@ -410,6 +704,10 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			senior:               SeniorProvisions{age: 65, credit: 40},
+			retirement:           RetirementProvisions{pensionExclusion: 31110, includesIRA: true, militaryExclusion: -1},
+			partYearMethod:       sourceOnly,
+			reciprocity:          []string{"IL", "IN", "MI", "OH", "VA", "WV", "WI"},
 			single: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.050},
@@ -431,17 +729,21 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.05}, refundable: true},
+			retirement:           RetirementProvisions{pensionExclusion: 6000, exclusionAge: 65, includesIRA: true, militaryExclusion: -1},
 			single: FilingStatus{
 				brackets:          []int{0, 12500, 50000},
 				rates:             []float64{0.0185, 0.035, 0.0425},
 				standardDeduction: 0,
 				personalExemption: 4500,
+				plan529Limit:      2400,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 25000, 100000},
 				rates:             []float64{0.0185, 0.035, 0.0425},
 				standardDeduction: 0,
 				personalExemption: 9000,
+				plan529Limit:      4800,
 			},
 		},
 		{
@@ -452,17 +754,21 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.25, 0.12}, refundable: true},
+			retirement:           RetirementProvisions{pensionExclusion: 25000, includesIRA: true, militaryExclusion: -1},
 			single: FilingStatus{
-				brackets:          []int{0, 23000, 54450},
-				rates:             []float64{0.058, 0.0675, 0.0715},
-				standardDeduction: 12950,
-				personalExemption: 4450,
+				brackets:            []int{0, 23000, 54450},
+				rates:               []float64{0.058, 0.0675, 0.0715},
+				standardDeduction:   12950,
+				additionalDeduction: 1750,
+				personalExemption:   4450,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 46000, 108900},
-				rates:             []float64{0.058, 0.0675, 0.0715},
-				standardDeduction: 25900,
-				personalExemption: 8900,
+				brackets:            []int{0, 46000, 108900},
+				rates:               []float64{0.058, 0.0675, 0.0715},
+				standardDeduction:   25900,
+				additionalDeduction: 1400,
+				personalExemption:   8900,
 			},
 		},
 		{
@@ -473,17 +779,25 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.45}, refundable: true},
+			senior:               SeniorProvisions{age: 65, exemption: 1000, blindExemption: 1000},
+			retirement:           RetirementProvisions{pensionExclusion: 34300, exclusionAge: 65, militaryExclusion: 12500},
+			partYearMethod:       sourceOnly,
+			reciprocity:          []string{"DC", "PA", "VA", "WV"},
+			withholding:          WithholdingFormula{allowance: 3200},
 			single: FilingStatus{
 				brackets:          []int{0, 1000, 2000, 3000, 100000, 125000, 150000, 250000},
 				rates:             []float64{0.02, 0.03, 0.04, 0.0475, 0.05, 0.0525, 0.055, 0.0575},
 				standardDeduction: 2350,
 				personalExemption: 3200,
+				plan529Limit:      2500,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 1000, 2000, 3000, 150000, 175000, 225000, 300000},
 				rates:             []float64{0.02, 0.03, 0.04, 0.0475, 0.05, 0.0525, 0.055, 0.0575},
 				standardDeduction: 4700,
 				personalExemption: 6400,
+				plan529Limit:      5000,
 			},
 		},
 		{
@@ -494,17 +808,32 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.30}, refundable: true},
+			senior:               SeniorProvisions{age: 65, exemption: 700, blindExemption: 2200},
+			retirement:           RetirementProvisions{militaryExclusion: -1},
+			partYearMethod:       sourceOnly,
+			estimates: EstimatedTaxRules{
+				currentYearShare: 0.8,
+				priorYearShare:   1.0,
+				highIncomeShare:  1.0,
+				highIncomeAGI:    150000,
+				installments:     []float64{0.25, 0.25, 0.25, 0.25},
+				minimumOwed:      400,
+				penaltyRate:      0.05,
+			},
 			single: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.05},
 				standardDeduction: 0,
 				personalExemption: 4400,
+				plan529Limit:      1000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.05},
 				standardDeduction: 0,
 				personalExemption: 8800,
+				plan529Limit:      2000,
 			},
 		},
 		{
@@ -515,17 +844,24 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.06}, refundable: true},
+			senior:               SeniorProvisions{age: 65, retirementExclusion: 12127},
+			retirement:           RetirementProvisions{militaryExclusion: -1},
+			partYearMethod:       sourceOnly,
+			reciprocity:          []string{"IL", "IN", "KY", "MN", "OH", "WI"},
 			single: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0425},
 				standardDeduction: 0,
 				personalExemption: 5000,
+				plan529Limit:      5000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0425},
 				standardDeduction: 0,
 				personalExemption: 10000,
+				plan529Limit:      10000,
 			},
 		},
 		{
@@ -536,17 +872,23 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1, militaryExclusion: -1},
+			reciprocity:          []string{"MI", "ND"},
 			single: FilingStatus{
-				brackets:          []int{0, 28080, 92230, 171220},
-				rates:             []float64{0.0535, 0.068, 0.0785, 0.0985},
-				standardDeduction: 12900,
-				personalExemption: 0,
+				brackets:            []int{0, 28080, 92230, 171220},
+				rates:               []float64{0.0535, 0.068, 0.0785, 0.0985},
+				standardDeduction:   12900,
+				additionalDeduction: 1750,
+				personalExemption:   0,
+				plan529Limit:        1500,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 41050, 163060, 284810},
-				rates:             []float64{0.0535, 0.068, 0.0785, 0.0985},
-				standardDeduction: 25800,
-				personalExemption: 0,
+				brackets:            []int{0, 41050, 163060, 284810},
+				rates:               []float64{0.0535, 0.068, 0.0785, 0.0985},
+				standardDeduction:   25800,
+				additionalDeduction: 1400,
+				personalExemption:   0,
+				plan529Limit:        3000,
 			},
 		},
 		{
@@ -557,17 +899,22 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			senior:               SeniorProvisions{age: 65, exemption: 1500, blindExemption: 1500},
+			retirement:           RetirementProvisions{pensionExclusion: -1, includesIRA: true, militaryExclusion: -1},
+			partYearMethod:       sourceOnly,
 			single: FilingStatus{
 				brackets:          []int{5000, 10000},
 				rates:             []float64{0.04, 0.05},
 				standardDeduction: 2300,
 				personalExemption: 6000,
+				plan529Limit:      10000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{5000, 10000},
 				rates:             []float64{0.04, 0.05},
 				standardDeduction: 4600,
 				personalExemption: 12000,
+				plan529Limit:      20000,
 			},
 		},
 		{
@@ -578,17 +925,24 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1, militaryExclusion: -1},
 			single: FilingStatus{
-				brackets:          []int{108, 1088, 2176, 3264, 4352, 5440, 6528, 7616, 8704},
-				rates:             []float64{0.015, 0.02, 0.025, 0.03, 0.035, 0.04, 0.045, 0.05, 0.054},
-				standardDeduction: 12950,
-				personalExemption: 0,
+				brackets:            []int{108, 1088, 2176, 3264, 4352, 5440, 6528, 7616, 8704},
+				rates:               []float64{0.015, 0.02, 0.025, 0.03, 0.035, 0.04, 0.045, 0.05, 0.054},
+				standardDeduction:   12950,
+				additionalDeduction: 1750,
+				personalExemption:   0,
+				socialSecurityLimit: 85000,
+				plan529Limit:        8000,
 			},
 			couple: FilingStatus{
-				brackets:          []int{108, 1088, 2176, 3264, 4352, 5440, 6528, 7616, 8704},
-				rates:             []float64{0.015, 0.02, 0.025, 0.03, 0.035, 0.04, 0.045, 0.05, 0.054},
-				standardDeduction: 25900,
-				personalExemption: 0,
+				brackets:            []int{108, 1088, 2176, 3264, 4352, 5440, 6528, 7616, 8704},
+				rates:               []float64{0.015, 0.02, 0.025, 0.03, 0.035, 0.04, 0.045, 0.05, 0.054},
+				standardDeduction:   25900,
+				additionalDeduction: 1400,
+				personalExemption:   0,
+				socialSecurityLimit: 100000,
+				plan529Limit:        16000,
 			},
 		},
 		{
@@ -599,17 +953,22 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0}, // 2% credit on capital gains (ignored for now)
+			eitc:                 StateEITC{rates: []float64{0.03}, refundable: true},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1},
+			reciprocity:          []string{"ND"},
 			single: FilingStatus{
 				brackets:          []int{0, 3100, 5500, 8400, 11400, 14600, 18800},
 				rates:             []float64{0.01, 0.02, 0.03, 0.04, 0.05, 0.06, 0.0675},
 				standardDeduction: 4830,
 				personalExemption: 2580,
+				plan529Limit:      3000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 3100, 5500, 8400, 11400, 14600, 18800},
 				rates:             []float64{0.01, 0.02, 0.03, 0.04, 0.05, 0.06, 0.0675},
 				standardDeduction: 9660,
 				personalExemption: 5160,
+				plan529Limit:      6000,
 			},
 		},
 		{
@@ -620,17 +979,26 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    true,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.10}, refundable: true},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 0.5, militaryExclusion: -1},
+			convenienceRule:      true,
 			single: FilingStatus{
-				brackets:          []int{0, 3440, 20590, 33180},
-				rates:             []float64{0.0246, 0.0351, 0.0501, 0.0684},
-				standardDeduction: 7350,
-				personalExemption: 146,
+				brackets:            []int{0, 3440, 20590, 33180},
+				rates:               []float64{0.0246, 0.0351, 0.0501, 0.0684},
+				standardDeduction:   7350,
+				additionalDeduction: 1750,
+				personalExemption:   146,
+				socialSecurityLimit: 44460,
+				plan529Limit:        10000,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 6860, 41190, 66360},
-				rates:             []float64{0.0246, 0.0351, 0.0501, 0.0684},
-				standardDeduction: 14700,
-				personalExemption: 292,
+				brackets:            []int{0, 6860, 41190, 66360},
+				rates:               []float64{0.0246, 0.0351, 0.0501, 0.0684},
+				standardDeduction:   14700,
+				additionalDeduction: 1400,
+				personalExemption:   292,
+				socialSecurityLimit: 59100,
+				plan529Limit:        10000,
 			},
 		},
 		{
@@ -662,6 +1030,7 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{0.0, 0.0, 0.05},
+			senior:               SeniorProvisions{age: 65, retirementExclusion: 1200},
 			single: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0},
@@ -683,17 +1052,35 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.40}, refundable: true},
+			senior:               SeniorProvisions{age: 65, exemption: 1000, blindExemption: 1000},
+			retirement:           RetirementProvisions{pensionExclusion: 75000, exclusionAge: 62, includesIRA: true},
+			pretax:               PretaxProvisions{taxesHSA: true, taxesFSA: true},
+			partYearMethod:       sourceOnly,
+			reciprocity:          []string{"PA"},
+			estimates: EstimatedTaxRules{
+				currentYearShare: 0.8,
+				priorYearShare:   1.0,
+				highIncomeShare:  1.0,
+				highIncomeAGI:    150000,
+				installments:     []float64{0.25, 0.25, 0.25, 0.25},
+				minimumOwed:      400,
+				penaltyRate:      0.05,
+			},
+			withholding: WithholdingFormula{personalAllowance: 1000, allowance: 1000},
 			single: FilingStatus{
 				brackets:          []int{0, 20000, 35000, 40000, 75000, 500000, 1000000},
 				rates:             []float64{0.014, 0.0175, 0.035, 0.05525, 0.0637, 0.0897, 0.1075},
 				standardDeduction: 0,
 				personalExemption: 1000,
+				plan529Limit:      10000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 20000, 50000, 70000, 80000, 150000, 500000, 1000000},
 				rates:             []float64{0.014, 0.0175, 0.0245, 0.035, 0.05525, 0.0637, 0.0897, 0.1075},
 				standardDeduction: 0,
 				personalExemption: 2000,
+				plan529Limit:      10000,
 			},
 		},
 		{
@@ -704,17 +1091,25 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, -0.4, 1.0}, // 40% deduction of capital gains
+			eitc:                 StateEITC{rates: []float64{0.25}, refundable: true},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1},
 			single: FilingStatus{
-				brackets:          []int{0, 5500, 11000, 16000, 210000},
-				rates:             []float64{0.017, 0.032, 0.047, 0.049, 0.059},
-				standardDeduction: 12950,
-				personalExemption: 0,
+				brackets:            []int{0, 5500, 11000, 16000, 210000},
+				rates:               []float64{0.017, 0.032, 0.047, 0.049, 0.059},
+				standardDeduction:   12950,
+				additionalDeduction: 1750,
+				personalExemption:   0,
+				socialSecurityLimit: 100000,
+				plan529Limit:        -1,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 8000, 16000, 24000, 315000},
-				rates:             []float64{0.017, 0.032, 0.047, 0.049, 0.059},
-				standardDeduction: 25900,
-				personalExemption: 0,
+				brackets:            []int{0, 8000, 16000, 24000, 315000},
+				rates:               []float64{0.017, 0.032, 0.047, 0.049, 0.059},
+				standardDeduction:   25900,
+				additionalDeduction: 1400,
+				personalExemption:   0,
+				socialSecurityLimit: 150000,
+				plan529Limit:        -1,
 			},
 		},
 		{
@@ -725,17 +1120,40 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.30}, refundable: true},
+			retirement:           RetirementProvisions{pensionExclusion: 20000, exclusionAge: 59, includesIRA: true, militaryExclusion: -1},
+			convenienceRule:      true,
+			estimates: EstimatedTaxRules{
+				currentYearShare: 0.9,
+				priorYearShare:   1.0,
+				highIncomeShare:  1.1,
+				highIncomeAGI:    150000,
+				installments:     []float64{0.25, 0.25, 0.25, 0.25},
+				minimumOwed:      300,
+				penaltyRate:      0.05,
+			},
+			withholding: WithholdingFormula{allowance: 1000},
 			single: FilingStatus{
 				brackets:          []int{0, 8500, 11700, 13900, 80650, 215400, 1077550, 5000000, 25000000},
 				rates:             []float64{0.04, 0.045, 0.0525, 0.0585, 0.0625, 0.0685, 0.0965, 0.103, 0.109},
 				standardDeduction: 8000,
 				personalExemption: 0,
+				plan529Limit:      5000,
+				recaptures: []Recapture{
+					// supplemental tax: the benefit of the lower brackets is recaptured over $50,000 of AGI
+					{kind: flatRate, start: 107650, step: 50000},
+				},
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 17150, 23600, 27900, 161550, 323200, 2155350, 5000000, 25000000},
 				rates:             []float64{0.04, 0.045, 0.0525, 0.0585, 0.0625, 0.0685, 0.0965, 0.103, 0.109},
 				standardDeduction: 16050,
 				personalExemption: 0,
+				plan529Limit:      10000,
+				recaptures: []Recapture{
+					// supplemental tax: the benefit of the lower brackets is recaptured over $50,000 of AGI
+					{kind: flatRate, start: 107650, step: 50000},
+				},
 			},
 		},
 		{
@@ -746,6 +1164,8 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			retirement:           RetirementProvisions{militaryExclusion: -1},
+			withholding:          WithholdingFormula{allowance: 2500},
 			single: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0499},
@@ -767,17 +1187,23 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, -0.4, 1.0},
+			retirement:           RetirementProvisions{militaryExclusion: -1},
+			reciprocity:          []string{"MN", "MT"},
 			single: FilingStatus{
-				brackets:          []int{0, 40525, 98100, 204675, 445000},
-				rates:             []float64{0.011, 0.0204, 0.0227, 0.0264, 0.029},
-				standardDeduction: 12950,
-				personalExemption: 0,
+				brackets:            []int{0, 40525, 98100, 204675, 445000},
+				rates:               []float64{0.011, 0.0204, 0.0227, 0.0264, 0.029},
+				standardDeduction:   12950,
+				additionalDeduction: 1750,
+				personalExemption:   0,
+				plan529Limit:        5000,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 67700, 163550, 249150, 445000},
-				rates:             []float64{0.011, 0.0204, 0.0227, 0.0264, 0.029},
-				standardDeduction: 25900,
-				personalExemption: 0,
+				brackets:            []int{0, 67700, 163550, 249150, 445000},
+				rates:               []float64{0.011, 0.0204, 0.0227, 0.0264, 0.029},
+				standardDeduction:   25900,
+				additionalDeduction: 1400,
+				personalExemption:   0,
+				plan529Limit:        10000,
 			},
 		},
 		{
@@ -785,20 +1211,30 @@ func initializeStates(income, capitalGains, dividends *float64,
 			abbrev:               "OH",
 			dependentExemption:   2400,
 			dependentIsCredit:    false,
+			dependentPhaseout:    Phaseout{start: 40000, end: 120000, step: 40000, reduction: 250},
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.30}, refundable: false},
+			senior:               SeniorProvisions{age: 65, credit: 50},
+			retirement:           RetirementProvisions{militaryExclusion: -1},
+			reciprocity:          []string{"IN", "KY", "MI", "PA", "WV"},
+			withholding:          WithholdingFormula{personalAllowance: 650, allowance: 650},
 			single: FilingStatus{
 				brackets:          []int{25000, 44250, 88450, 110650},
 				rates:             []float64{0.02765, 0.03226, 0.03688, 0.0399},
 				standardDeduction: 0,
 				personalExemption: 2400,
+				exemptionPhaseout: Phaseout{start: 40000, end: 120000, step: 40000, reduction: 250}, // $2,400 to $40k, $2,150 to $80k, $1,900 above
+				plan529Limit:      4000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{25000, 44250, 88450, 110650},
 				rates:             []float64{0.02765, 0.03226, 0.03688, 0.0399},
 				standardDeduction: 0,
 				personalExemption: 4800,
+				exemptionPhaseout: Phaseout{start: 40000, end: 120000, step: 40000, reduction: 500},
+				plan529Limit:      4000,
 			},
 		},
 		{
@@ -809,17 +1245,22 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.05}, refundable: true},
+			retirement:           RetirementProvisions{pensionExclusion: 10000, includesIRA: true},
+			taxesInStateMunis:    true,
 			single: FilingStatus{
 				brackets:          []int{0, 1000, 2500, 3750, 4900, 7200},
 				rates:             []float64{0.0025, 0.0075, 0.0175, 0.0275, 0.0375, 0.0475},
 				standardDeduction: 6350,
 				personalExemption: 1000,
+				plan529Limit:      10000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 2000, 5000, 7500, 9800, 12200},
 				rates:             []float64{0.0025, 0.0075, 0.0175, 0.0275, 0.0375, 0.0475},
 				standardDeduction: 12700,
 				personalExemption: 2000,
+				plan529Limit:      20000,
 			},
 		},
 		{
@@ -830,17 +1271,20 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    true,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.09}, refundable: true},
 			single: FilingStatus{
-				brackets:          []int{0, 3650, 9200, 125000},
-				rates:             []float64{0.0475, 0.0675, 0.0875, 0.099},
-				standardDeduction: 2420,
-				personalExemption: 219,
+				brackets:            []int{0, 3650, 9200, 125000},
+				rates:               []float64{0.0475, 0.0675, 0.0875, 0.099},
+				standardDeduction:   2420,
+				additionalDeduction: 1200,
+				personalExemption:   219,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 7300, 18400, 250000},
-				rates:             []float64{0.0475, 0.0675, 0.0875, 0.099},
-				standardDeduction: 4840,
-				personalExemption: 436,
+				brackets:            []int{0, 7300, 18400, 250000},
+				rates:               []float64{0.0475, 0.0675, 0.0875, 0.099},
+				standardDeduction:   4840,
+				additionalDeduction: 1000,
+				personalExemption:   436,
 			},
 		},
 		{
@@ -851,17 +1295,33 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			retirement:           RetirementProvisions{pensionExclusion: -1, includesIRA: true, militaryExclusion: -1},
+			pretax:               PretaxProvisions{taxes401k: true},
+			partYearMethod:       sourceOnly,
+			reciprocity:          []string{"IN", "MD", "NJ", "OH", "VA", "WV"},
+			convenienceRule:      true,
+			estimates: EstimatedTaxRules{
+				currentYearShare: 0.9,
+				priorYearShare:   1.0,
+				highIncomeShare:  1.0,
+				highIncomeAGI:    150000,
+				installments:     []float64{0.25, 0.25, 0.25, 0.25},
+				minimumOwed:      246,
+				penaltyRate:      0.05,
+			},
 			single: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0307},
 				standardDeduction: 0,
 				personalExemption: 0,
+				plan529Limit:      16000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0307},
 				standardDeduction: 0,
 				personalExemption: 0,
+				plan529Limit:      32000,
 			},
 		},
 		{
@@ -872,17 +1332,23 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.15}, refundable: true},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1, pensionExclusion: 20000, exclusionAge: 67, includesIRA: true},
 			single: FilingStatus{
-				brackets:          []int{0, 68200, 155050},
-				rates:             []float64{0.0375, 0.0475, 0.0599},
-				standardDeduction: 9300,
-				personalExemption: 4350,
+				brackets:            []int{0, 68200, 155050},
+				rates:               []float64{0.0375, 0.0475, 0.0599},
+				standardDeduction:   9300,
+				personalExemption:   4350,
+				socialSecurityLimit: 86350,
+				plan529Limit:        500,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 68200, 155050},
-				rates:             []float64{0.0375, 0.0475, 0.0599},
-				standardDeduction: 18600,
-				personalExemption: 8700,
+				brackets:            []int{0, 68200, 155050},
+				rates:               []float64{0.0375, 0.0475, 0.0599},
+				standardDeduction:   18600,
+				personalExemption:   8700,
+				socialSecurityLimit: 107950,
+				plan529Limit:        1000,
 			},
 		},
 		{
@@ -893,17 +1359,24 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, -0.44, 1.0},
+			eitc:                 StateEITC{rates: []float64{1.25}, refundable: false},
+			senior:               SeniorProvisions{age: 65, retirementExclusion: 15000, earnedExclusion: 15000},
+			retirement:           RetirementProvisions{pensionExclusion: 3000, includesIRA: true, militaryExclusion: -1},
 			single: FilingStatus{
-				brackets:          []int{0, 3200, 6410, 9620, 12820, 16040},
-				rates:             []float64{0.0, 0.03, 0.04, 0.05, 0.06, 0.07},
-				standardDeduction: 12950,
-				personalExemption: 0,
+				brackets:            []int{0, 3200, 6410, 9620, 12820, 16040},
+				rates:               []float64{0.0, 0.03, 0.04, 0.05, 0.06, 0.07},
+				standardDeduction:   12950,
+				additionalDeduction: 1750,
+				personalExemption:   0,
+				plan529Limit:        -1,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 3200, 6410, 9620, 12820, 16040},
-				rates:             []float64{0.0, 0.03, 0.04, 0.05, 0.06, 0.07},
-				standardDeduction: 25900,
-				personalExemption: 0,
+				brackets:            []int{0, 3200, 6410, 9620, 12820, 16040},
+				rates:               []float64{0.0, 0.03, 0.04, 0.05, 0.06, 0.07},
+				standardDeduction:   25900,
+				additionalDeduction: 1400,
+				personalExemption:   0,
+				plan529Limit:        -1,
 			},
 		},
 		{
@@ -977,17 +1450,21 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: true,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1, militaryExclusion: -1},
+			pretax:               PretaxProvisions{plan529CreditRate: 0.0485},
 			single: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0495},
 				standardDeduction: 777,
 				personalExemption: 0,
+				plan529Limit:      2290,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0},
 				rates:             []float64{0.0495},
 				standardDeduction: 1554,
 				personalExemption: 0,
+				plan529Limit:      4580,
 			},
 		},
 		{
@@ -998,17 +1475,26 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0}, // there's a special case here too (ignored for now)
+			eitc:                 StateEITC{rates: []float64{0.38}, refundable: true},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1, militaryExclusion: -1},
+			pretax:               PretaxProvisions{plan529CreditRate: 0.10},
 			single: FilingStatus{
-				brackets:          []int{0, 40950, 99200, 206950},
-				rates:             []float64{0.0335, 0.066, 0.076, 0.0875},
-				standardDeduction: 6350,
-				personalExemption: 4350,
+				brackets:            []int{0, 40950, 99200, 206950},
+				rates:               []float64{0.0335, 0.066, 0.076, 0.0875},
+				standardDeduction:   6350,
+				additionalDeduction: 1050,
+				personalExemption:   4350,
+				socialSecurityLimit: 45000,
+				plan529Limit:        2500,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 68400, 165350, 251950},
-				rates:             []float64{0.0335, 0.066, 0.076, 0.0875},
-				standardDeduction: 12700,
-				personalExemption: 8700,
+				brackets:            []int{0, 68400, 165350, 251950},
+				rates:               []float64{0.0335, 0.066, 0.076, 0.0875},
+				standardDeduction:   12700,
+				additionalDeduction: 1050,
+				personalExemption:   8700,
+				socialSecurityLimit: 60000,
+				plan529Limit:        5000,
 			},
 		},
 		{
@@ -1019,17 +1505,24 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.15}, refundable: true},
+			senior:               SeniorProvisions{age: 65, exemption: 800, blindExemption: 800},
+			partYearMethod:       sourceOnly,
+			reciprocity:          []string{"DC", "KY", "MD", "PA", "WV"},
+			withholding:          WithholdingFormula{standardDeduction: 8000, personalAllowance: 930, allowance: 930},
 			single: FilingStatus{
 				brackets:          []int{0, 3000, 5000, 17000},
 				rates:             []float64{0.02, 0.03, 0.05, 0.0575},
 				standardDeduction: 4500,
 				personalExemption: 930,
+				plan529Limit:      4000,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 3000, 5000, 17000},
 				rates:             []float64{0.02, 0.03, 0.05, 0.0575},
 				standardDeduction: 9000,
 				personalExemption: 1860,
+				plan529Limit:      4000,
 			},
 		},
 		{
@@ -1061,17 +1554,25 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			senior:               SeniorProvisions{age: 65, retirementExclusion: 8000, earnedExclusion: 8000},
+			retirement:           RetirementProvisions{socialSecurityTaxed: 1, militaryExclusion: -1},
+			partYearMethod:       sourceOnly,
+			reciprocity:          []string{"KY", "MD", "OH", "PA", "VA"},
 			single: FilingStatus{
-				brackets:          []int{0, 10000, 25000, 40000, 60000},
-				rates:             []float64{0.03, 0.04, 0.045, 0.06, 0.065},
-				standardDeduction: 0,
-				personalExemption: 2000,
+				brackets:            []int{0, 10000, 25000, 40000, 60000},
+				rates:               []float64{0.03, 0.04, 0.045, 0.06, 0.065},
+				standardDeduction:   0,
+				personalExemption:   2000,
+				socialSecurityLimit: 50000,
+				plan529Limit:        -1,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 10000, 25000, 40000, 60000},
-				rates:             []float64{0.03, 0.04, 0.045, 0.06, 0.065},
-				standardDeduction: 0,
-				personalExemption: 4000,
+				brackets:            []int{0, 10000, 25000, 40000, 60000},
+				rates:               []float64{0.03, 0.04, 0.045, 0.06, 0.065},
+				standardDeduction:   0,
+				personalExemption:   4000,
+				socialSecurityLimit: 100000,
+				plan529Limit:        -1,
 			},
 		},
 		{
@@ -1082,17 +1583,26 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{0.0, 0.04, 0.11, 0.34}, refundable: true},
+			senior:               SeniorProvisions{age: 65, exemption: 250},
+			retirement:           RetirementProvisions{militaryExclusion: -1},
+			reciprocity:          []string{"IL", "IN", "KY", "MI"},
+			taxesInStateMunis:    true,
 			single: FilingStatus{
 				brackets:          []int{0, 12760, 25520, 280950},
 				rates:             []float64{0.0354, 0.0465, 0.053, 0.0765},
 				standardDeduction: 11790,
 				personalExemption: 700,
+				deductionPhaseout: Phaseout{start: 17780, reduction: 0.12},
+				plan529Limit:      3560,
 			},
 			couple: FilingStatus{
 				brackets:          []int{0, 17010, 34030, 374030},
 				rates:             []float64{0.0354, 0.0465, 0.053, 0.0765},
 				standardDeduction: 21820,
 				personalExemption: 1400,
+				deductionPhaseout: Phaseout{start: 25600, reduction: 0.19778},
+				plan529Limit:      3560,
 			},
 		},
 		{
@@ -1124,40 +1634,66 @@ func initializeStates(income, capitalGains, dividends *float64,
 			stdDeductionIsCredit: false,
 			exemptionIsCredit:    false,
 			incomeTypesTaxed:     []float32{1.0, 1.0, 1.0},
+			eitc:                 StateEITC{rates: []float64{1.00, 0.70}, refundable: true}, // DC's own childless credit is approximated as the federal one
+			partYearMethod:       sourceOnly,
+			exemptsNonresidents:  true,
+			exemptsOtherMunis:    true,
 			single: FilingStatus{
-				brackets:          []int{0, 10000, 40000, 60000, 250000, 500000, 1000000},
-				rates:             []float64{0.04, 0.06, 0.065, 0.085, 0.0925, 0.0975, 0.1075},
-				standardDeduction: 12950,
-				personalExemption: 0,
+				brackets:            []int{0, 10000, 40000, 60000, 250000, 500000, 1000000},
+				rates:               []float64{0.04, 0.06, 0.065, 0.085, 0.0925, 0.0975, 0.1075},
+				standardDeduction:   12950,
+				additionalDeduction: 1750,
+				personalExemption:   0,
+				plan529Limit:        4000,
 			},
 			couple: FilingStatus{
-				brackets:          []int{0, 10000, 40000, 60000, 250000, 500000, 1000000},
-				rates:             []float64{0.04, 0.06, 0.065, 0.085, 0.0925, 0.0975, 0.1075},
-				standardDeduction: 25900,
-				personalExemption: 0,
+				brackets:            []int{0, 10000, 40000, 60000, 250000, 500000, 1000000},
+				rates:               []float64{0.04, 0.06, 0.065, 0.085, 0.0925, 0.0975, 0.1075},
+				standardDeduction:   25900,
+				additionalDeduction: 1400,
+				personalExemption:   0,
+				plan529Limit:        8000,
 			},
 		},
 	}
 	for _, state := range states {
-		state.incomeTax, state.effectiveRate = state.calcIncomeTax(
-			income, capitalGains, dividends, federalTax, numDependents, mfj)
+		state.incomeTax, state.effectiveRate = state.calcIncomeTax(household, federal)
 	}
 	return &states
 }
 
-func initializeFederal(income, capitalGains, dividends *float64, mfj, qualified bool) *Federal {
+func initializeFederal(household *Household) *Federal {
 	federal := Federal{
 		name:               "Federal",
 		abbrev:             "USA",
 		medicareRate:       0.0145,
 		socialSecurityRate: 0.062,
-		socialSecurityCap:  147000, // of taxable income
+		socialSecurityCap:  147000, // of wages
+		eitcInvestmentCap:  10300,
+		estimates: EstimatedTaxRules{
+			currentYearShare: 0.9,
+			priorYearShare:   1.0,
+			highIncomeShare:  1.1,
+			highIncomeAGI:    150000,
+			installments:     []float64{0.25, 0.25, 0.25, 0.25},
+			minimumOwed:      1000,
+			penaltyRate:      0.05,
+		},
 		single: FedFilingStatus{
 			incomeBrackets:       []int{0, 10275, 41775, 89075, 170050, 215950, 539900},
 			incomeRates:          []float64{0.10, 0.12, 0.22, 0.24, 0.32, 0.35, 0.37},
 			capitalGainsBrackets: []int{0, 41675, 459750},
 			capitalGainsRates:    []float64{0.0, 0.15, 0.20},
 			standardDeduction:    12950,
+			additionalDeduction:  1750,
+			socialSecurityBases:  []int{25000, 34000},
+			eitc: []EITCSchedule{
+				{creditRate: 0.0765, earnedAmount: 7320, phaseoutRate: 0.0765, phaseoutStart: 9160},
+				{creditRate: 0.34, earnedAmount: 10980, phaseoutRate: 0.1598, phaseoutStart: 20130},
+				{creditRate: 0.40, earnedAmount: 15410, phaseoutRate: 0.2106, phaseoutStart: 20130},
+				{creditRate: 0.45, earnedAmount: 15410, phaseoutRate: 0.2106, phaseoutStart: 20130},
+			},
+			additionalMedicareThreshold: 200000,
 		},
 		couple: FedFilingStatus{
 			incomeBrackets:       []int{0, 20550, 83550, 178150, 340100, 431900, 647850},
@@ -1165,58 +1701,264 @@ func initializeFederal(income, capitalGains, dividends *float64, mfj, qualified
 			capitalGainsBrackets: []int{0, 83350, 517200},
 			capitalGainsRates:    []float64{0.0, 0.15, 0.20},
 			standardDeduction:    25900,
+			additionalDeduction:  1400,
+			socialSecurityBases:  []int{32000, 44000},
+			eitc: []EITCSchedule{
+				{creditRate: 0.0765, earnedAmount: 7320, phaseoutRate: 0.0765, phaseoutStart: 15290},
+				{creditRate: 0.34, earnedAmount: 10980, phaseoutRate: 0.1598, phaseoutStart: 26260},
+				{creditRate: 0.40, earnedAmount: 15410, phaseoutRate: 0.2106, phaseoutStart: 26260},
+				{creditRate: 0.45, earnedAmount: 15410, phaseoutRate: 0.2106, phaseoutStart: 26260},
+			},
+			additionalMedicareThreshold: 250000,
 		},
+		additionalMedicareRate: 0.009,
 	}
-	federal.incomeTax, federal.effectiveRate = federal.calcFederalIncomeTax(
-		*income, *capitalGains, *dividends, mfj, qualified)
+	federal.incomeTax, federal.effectiveRate = federal.calcFederalIncomeTax(household)
 	return &federal
 }
 
 func main() {
-	income := flag.Float64("income", 0, "Annual taxable income")
-	capitalGains := flag.Float64("cg", 0, "Capital Gains earned")
-	dividends := flag.Float64("interest", 0, "Dividends and interest earned")
-	qualified := flag.Bool("qualified", false, "Are the dividends qualified? (default false)")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "yield":
+			runYield(os.Args[2:])
+			return
+		case "estimates":
+			runEstimates(os.Args[2:])
+			return
+		case "paycheck":
+			runPaycheck(os.Args[2:])
+			return
+		case "grossup":
+			runGrossUp(os.Args[2:])
+			return
+		case "breakeven":
+			runBreakEven(os.Args[2:])
+			return
+		case "explain":
+			runExplain(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "batch":
+			runBatch(os.Args[2:])
+			return
+		case "taxsim":
+			runTaxsim(os.Args[2:])
+			return
+		case "tui":
+			runTUI(os.Args[2:])
+			return
+		}
+	}
+
+	newHousehold := householdFlags(flag.CommandLine)
 	toCSV := flag.Bool("csv", false, "Write the output to a CSV file?")
-	numSteps := flag.Int("steps", 100, "The number of discrete points between 0 and income for CSV output")
-	mfj := flag.Bool("joint", false, "Married filing jointly? (default false)")
-	numDependents := flag.Int("dependents", 0, "number of dependents (default 0)")
+	numSteps := flag.Int("steps", 100, "The number of discrete points between 0 and income for CSV output and plots")
+	ascending := flag.Bool("ascending", false, "Rank the states from lowest to highest effective rate")
+	plotFile := flag.String("plot", "", "Plot effective rate by income for the top states to this .svg or .png file")
+	top := flag.Int("top", 7, "The number of top-ranked states to plot or chart, 0 for all")
+	mapFile := flag.String("map", "", "Draw a tile map of the states colored by their rate to this .svg or .png file")
+	mapRate := flag.String("mapRate", "effective", "The rate to color the map by: effective or marginal")
+	mapLabels := flag.Bool("mapLabels", true, "Label the map's states with their abbreviation and rate")
+	chart := flag.Bool("chart", false, "Add sparklines, bars and a line chart of the top states to the report")
+	plotFederal := flag.Bool("plotFederal", false, "Include the federal effective rate in the plot")
+	xMax := flag.Float64("xmax", 0, "The highest income on the plot's x axis (default income)")
+	yMin := flag.Float64("ymin", 0, "The lowest effective rate on the plot's y axis, e.g. -0.05")
+	yMax := flag.Float64("ymax", 0, "The highest effective rate on the plot's y axis, e.g. 0.12 (automatic if -ymin and -ymax are 0)")
+	fromState := flag.String("from", "", "State moved out of during the year, e.g. CA (requires -to and -move)")
+	toState := flag.String("to", "", "State moved into during the year, e.g. TX")
+	moveDate := flag.String("move", "", "Date of the move, as YYYY-MM-DD")
+	homeState := flag.String("home", "", "State of residence, e.g. NJ (requires -work)")
+	workState := flag.String("work", "", "State the employer is in, e.g. NY (requires -home)")
+	remoteShare := flag.Float64("remote", 0, "Share of work days spent working from the home state, 0-1")
+	colFile := flag.String("col", "", "CSV of cost of living indexes (state, index and optionally area columns) to rank by")
+	format := flag.String("format", "text", "Output format of the 50-state report: text, json or html")
 	flag.Parse()
 
-	federal := initializeFederal(income, capitalGains, dividends, *mfj, *qualified)
-	states := initializeStates(income, capitalGains, dividends, federal.incomeTax, *numDependents, *mfj)
+	if *format != "text" && *format != "json" && *format != "html" {
+		usageError("-format must be text, json or html, got %q", *format)
+	}
+	if *format != "text" && (*fromState != "" || *homeState != "" || *colFile != "") {
+		usageError("-format=%s only applies to the 50-state report, not -from, -home or -col", *format)
+	}
+	if *plotFile != "" && (*fromState != "" || *homeState != "") {
+		usageError("-plot only applies to the 50-state report, not -from or -home")
+	}
+	if *chart && (*format != "text" || *colFile != "") {
+		usageError("-chart only applies to the text report, not -format=%s or -col", *format)
+	}
+	if *mapRate != "effective" && *mapRate != "marginal" {
+		usageError("-mapRate must be effective or marginal, got %q", *mapRate)
+	}
+	if *mapFile != "" && (*fromState != "" || *homeState != "") {
+		usageError("-map only applies to the 50-state report, not -from or -home")
+	}
+	if *numSteps < 1 {
+		usageError("-steps must be at least 1, got %d", *numSteps)
+	}
+	if (*yMin != 0 || *yMax != 0) && *yMax <= *yMin {
+		usageError("-ymax must be above -ymin, got %g and %g", *yMax, *yMin)
+	}
+
+	household := newHousehold()
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+
+	if *fromState != "" || *toState != "" || *moveDate != "" {
+		printRelocation(household, federal, states, *fromState, *toState, *moveDate)
+		return
+	}
+	if *homeState != "" || *workState != "" {
+		printCommute(household, federal, states, *homeState, *workState, *remoteShare)
+		return
+	}
 
-	sort.SliceStable(states[:], func(i, j int) bool {
-		return states[i].effectiveRate > states[j].effectiveRate
-	})
+	sortByEffectiveRate(states)
+	if *ascending {
+		for i, j := 0, len(states)-1; i < j; i, j = i+1, j-1 {
+			states[i], states[j] = states[j], states[i]
+		}
+	}
 
-	printResults(income, federal, states)
+	if *colFile != "" {
+		rows := readCostOfLiving(*colFile, states)
+		rankByPurchasingPower(household, federal, rows)
+		printCostOfLiving(household, rows)
+	} else if *format == "json" {
+		writeJSON(os.Stdout, newReport(household, federal, states))
+	} else if *format == "html" {
+		writeHTML(os.Stdout, household, federal, states, newSweep(household, *numSteps, federal, states), *ascending,
+			&PlotOptions{
+				top:     *top,
+				federal: *plotFederal,
+				xMax:    *xMax,
+				yMin:    *yMin,
+				yMax:    *yMax,
+			})
+	} else if *chart {
+		printResults(household, federal, states, newSweep(household, *numSteps, federal, states), *top)
+	} else {
+		printResults(household, federal, states, nil, 0)
+	}
 
 	if *toCSV {
-		writeToCSV(*income, *capitalGains, *dividends, *numSteps,
-			*numDependents, federal, states, *mfj, *qualified)
+		writeToCSV(household, *numSteps, federal, states)
+	}
+	if *plotFile != "" {
+		writePlot(*plotFile, *ascending, newSweep(household, *numSteps, federal, states), states, &PlotOptions{
+			top:     *top,
+			federal: *plotFederal,
+			xMax:    *xMax,
+			yMin:    *yMin,
+			yMax:    *yMax,
+		})
+	}
+	if *mapFile != "" {
+		writeMap(*mapFile, household, federal, states, *mapRate == "marginal", *mapLabels)
 	}
 }
 
-func printResults(income *float64, federal *Federal, states *[51]*State) {
-	fmt.Printf("\n50-State income tax report for income of $%.0f\n", *income)
-	fmt.Println("    State                Tax       Effective Rate")
-	fmt.Println("==================================================")
-	fmt.Printf("*   %-20s $%-8d %.3f%%\n", federal.name, federal.incomeTax, 100*federal.effectiveRate)
-	fmt.Println("==================================================")
-	for i := 0; i < 51; i++ {
-		fmt.Printf("%-3d %-20s $%-8d %.3f%%\n", i+1, states[i].name, states[i].incomeTax, 100*states[i].effectiveRate)
+// householdFlags defines the flags describing a household on `fs`, shared by every mode.
+// the returned function builds the household once `fs` has been parsed.
+func householdFlags(fs *flag.FlagSet) func() *Household {
+	income := fs.Float64("income", 0, "Annual taxable income")
+	capitalGains := fs.Float64("cg", 0, "Capital Gains earned")
+	dividends := fs.Float64("interest", 0, "Dividends and interest earned")
+	qualified := fs.Bool("qualified", false, "Are the dividends qualified? (default false)")
+	mfj := fs.Bool("joint", false, "Married filing jointly? (default false)")
+	numDependents := fs.Int("dependents", 0, "number of dependents (default 0)")
+	age := fs.Int("age", 0, "Age of the filer at the end of the year")
+	spouseAge := fs.Int("spouseAge", 0, "Age of the spouse at the end of the year, if filing jointly")
+	blind := fs.Bool("blind", false, "Is the filer blind? (default false)")
+	spouseBlind := fs.Bool("spouseBlind", false, "Is the spouse blind? (default false)")
+	socialSecurity := fs.Float64("ss", 0, "Social Security benefits received")
+	pension := fs.Float64("pension", 0, "Pension income, excluding military retirement")
+	militaryPension := fs.Float64("military", 0, "Military retirement pay")
+	iraDistributions := fs.Float64("ira", 0, "Traditional IRA and 401(k) distributions")
+	contributions401k := fs.Float64("401k", 0, "Pre-tax 401(k) contributions, out of income")
+	contributionsHSA := fs.Float64("hsa", 0, "Pre-tax HSA contributions, out of income")
+	contributionsFSA := fs.Float64("fsa", 0, "Pre-tax FSA contributions, out of income")
+	contributions529 := fs.Float64("529", 0, "529 plan contributions")
+	treasuryInterest := fs.Float64("treasury", 0, "U.S. Treasury interest")
+	inStateMuniInterest := fs.Float64("munis", 0, "Interest from municipal bonds issued by the state of residence")
+	otherMuniInterest := fs.Float64("otherMunis", 0, "Interest from municipal bonds issued by other states")
+	spouseIncome := fs.Float64("spouseIncome", 0, "The part of -income the spouse earned, if filing jointly; social security tax is capped per earner")
+	return func() *Household {
+		return &Household{
+			income:              *income,
+			capitalGains:        *capitalGains,
+			dividends:           *dividends,
+			qualified:           *qualified,
+			mfj:                 *mfj,
+			numDependents:       *numDependents,
+			age:                 *age,
+			spouseAge:           *spouseAge,
+			blind:               *blind,
+			spouseBlind:         *spouseBlind,
+			socialSecurity:      *socialSecurity,
+			pension:             *pension,
+			militaryPension:     *militaryPension,
+			iraDistributions:    *iraDistributions,
+			contributions401k:   *contributions401k,
+			contributionsHSA:    *contributionsHSA,
+			contributionsFSA:    *contributionsFSA,
+			contributions529:    *contributions529,
+			treasuryInterest:    *treasuryInterest,
+			inStateMuniInterest: *inStateMuniInterest,
+			otherMuniInterest:   *otherMuniInterest,
+			spouseIncome:        *spouseIncome,
+		}
 	}
-	fmt.Println("==================================================")
 }
 
-func writeToCSV(income, capitalGains, dividends float64,
-	numSteps, numDependents int, federal *Federal, states *[51]*State, mfj, qualified bool) {
-	// create an array of incomes sliced into `numSteps` steps
-	incomeArray := *getIncomeArray(income, numSteps)
-	capitalGainsArray := *getIncomeArray(capitalGains, numSteps)
-	dividendsArray := *getIncomeArray(dividends, numSteps)
+// printResults prints the ranked report. with a sweep, each row also gets a sparkline of its
+// effective rate across the sweep and a bar of its rate, and the top states are charted.
+func printResults(household *Household, federal *Federal, states *[51]*State, sweep *Sweep, top int) {
+	if sweep == nil {
+		fmt.Printf("\n50-State income tax report for income of $%.0f\n", household.income)
+		fmt.Println("    State                Tax       Effective Rate")
+		fmt.Println("==================================================")
+		fmt.Printf("*   %-20s $%-8d %.3f%%\n", federal.name, federal.incomeTax, 100*federal.effectiveRate)
+		fmt.Println("==================================================")
+		for i := 0; i < 51; i++ {
+			fmt.Printf("%-3d %-20s $%-8d %.3f%%\n", i+1, states[i].name, states[i].incomeTax, 100*states[i].effectiveRate)
+		}
+		fmt.Println("==================================================")
+		return
+	}
+
+	// every state's sparkline is on the same scale, and the bars are relative to the highest rate
+	stateRates := make([][]float64, 0, len(states))
+	maxRate := 0.0
+	for _, state := range states {
+		stateRates = append(stateRates, sweepRates(sweep, state.abbrev))
+		maxRate = math.Max(maxRate, state.effectiveRate)
+	}
+	lo, hi := rateRange(stateRates...)
+	federalRates := sweepRates(sweep, "")
+	federalLo, federalHi := rateRange(federalRates)
+	rule := strings.Repeat("=", 54+sparkWidth+barWidth)
+
+	fmt.Printf("\n50-State income tax report for income of $%.0f\n", household.income)
+	fmt.Printf("    State                Tax       Effective  %-*s Rate\n", sparkWidth,
+		fmt.Sprintf("$%.0f-$%.0f", sweep.Points[0].Income, sweep.Points[len(sweep.Points)-1].Income))
+	fmt.Println(rule)
+	fmt.Printf("*   %-20s $%-8d %-9s  %s\n", federal.name, federal.incomeTax,
+		fmt.Sprintf("%.3f%%", 100*federal.effectiveRate), sparkline(federalRates, federalLo, federalHi))
+	fmt.Println(rule)
+	for i := 0; i < 51; i++ {
+		fmt.Printf("%-3d %-20s $%-8d %-9s  %s %s\n", i+1, states[i].name, states[i].incomeTax,
+			fmt.Sprintf("%.3f%%", 100*states[i].effectiveRate), sparkline(stateRates[i], lo, hi), bar(states[i].effectiveRate, maxRate))
+	}
+	fmt.Println(rule)
+	fmt.Printf("state sparklines run from %.1f%% to %.1f%%, the federal one from %.1f%% to %.1f%%\n",
+		100*lo, 100*hi, 100*federalLo, 100*federalHi)
+	printLineChart(sweep, states, top)
+}
 
+func writeToCSV(household *Household, numSteps int, federal *Federal, states *[51]*State) {
 	// create the 2D array at runtime with make()
 	data := make([][]string, numSteps+1)
 	for i := range data {
@@ -1232,26 +1974,29 @@ func writeToCSV(income, capitalGains, dividends float64,
 	}
 
 	for i := 0; i < numSteps; i++ {
+		// the same household, scaled down to this income level. the federal column uses the
+		// row's gains and dividends too, like the state columns and their federal tax deductions
+		// do, rather than the full year's paired with a fraction of the income.
+		row := *household.sweepStep(i, numSteps)
+
 		// add the income level for this row
-		data[i+1][0] = strconv.FormatFloat(incomeArray[i], 'f', 2, 32)
+		data[i+1][0] = strconv.FormatFloat(row.income, 'f', 2, 32)
 
 		// add the federal effective rate for this income level
-		// calcFederalIncomeTax(income, capitalGains, dividends *float64, mfj, qualified bool) (int, float64)
-		federalTax, rate := federal.calcFederalIncomeTax(incomeArray[i], capitalGains, dividends, mfj, qualified)
+		_, rate := federal.calcFederalIncomeTax(&row)
 		data[i+1][1] = strconv.FormatFloat(rate, 'f', 6, 32)
 
 		// add all 50 States' + DC's effective rate for this income level
 		for j, state := range *states {
-			_, rate := state.calcIncomeTax(
-				&incomeArray[i], &capitalGainsArray[i], &dividendsArray[i],
-				federalTax, numDependents, mfj)
+			_, rate := state.calcIncomeTax(&row, federal)
 			data[i+1][j+2] = strconv.FormatFloat(rate, 'f', 6, 32)
 		}
 	}
 	// filenames are getting long... could use some encoding to reduce this... md5 checksum?
 	filename := fmt.Sprintf(
 		"./output/csv/income=%.0f_cg=%.0f_dividends=%.0f_qualified=%t_dependents=%d_mfj=%t_steps=%d.csv",
-		income, capitalGains, dividends, qualified, numDependents, mfj, numSteps)
+		household.income, household.capitalGains, household.dividends,
+		household.qualified, household.numDependents, household.mfj, numSteps)
 	file, err := os.Create(filename)
 	if err != nil {
 		panic(err)
@@ -1270,43 +2015,88 @@ func writeToCSV(income, capitalGains, dividends float64,
 	}
 }
 
-func getIncomeArray(income float64, numSteps int) *[]float64 {
-	stepSize := income / float64(numSteps)
-	incomes := make([]float64, numSteps)
-	for i := 0; i < numSteps; i++ {
-		incomes[i] = float64(stepSize * float64(i+1))
-	}
-	return &incomes
+func (state *State) calcIncomeTax(household *Household, federal *Federal) (int, float64) {
+	breakdown := state.calcBreakdown(household, federal, nil)
+	return int(breakdown.tax), breakdown.tax / breakdown.grossIncome
 }
 
-func (state *State) calcIncomeTax(income, capitalGains, dividends *float64,
-	federalTax, numDependents int, mfj bool) (int, float64) {
-	tax, taxableIncome, grossIncome := 0.0, (*income), ((*income) + (*capitalGains) + (*dividends))
+// calcBreakdown runs the state's calculation, writing each step to the worksheet if it isn't nil
+func (state *State) calcBreakdown(household *Household, federal *Federal, w *Worksheet) *StateBreakdown {
+	income, capitalGains, dividends := state.calcWages(household), household.capitalGains, household.dividends
+	dividends += household.ordinaryInterest + state.calcTaxableMuniInterest(household)
+	tax, grossIncome, agi := 0.0, household.grossIncome(), state.calcAGI(household, federal)
+	numDependents, numSeniors := household.numDependents, household.numSeniors(state.senior.age)
 	data := state.single
-	if mfj {
+	if household.mfj {
 		data = state.couple
 	}
+	b := &StateBreakdown{grossIncome: grossIncome}
+	w.add("gross income", grossIncome, 0, 0)
+
+	// age-based exclusions come off the income itself, before it's categorized below
+	retirement := state.calcRetirementIncome(household, federal)
+	beforeExclusions := income + retirement + capitalGains + dividends
+	retirement, income, capitalGains, dividends = state.senior.exclude(
+		numSeniors, retirement, income, capitalGains, dividends)
+	taxableIncome := income + retirement
+	b.income, b.retirementIncome, b.capitalGains, b.dividends = income, retirement, capitalGains, dividends
+	w.add("untaxed income and contributions", -(grossIncome - beforeExclusions), 0, 0)
+	w.add("age-based exclusions", -(beforeExclusions - income - retirement - capitalGains - dividends), 0, 0)
+	w.add("ordinary income", income, income, tax)
+	w.add("taxable retirement income", retirement, taxableIncome, tax)
 
-	dependentExemption := float64(state.dependentExemption * numDependents)
+	dependentExemption := state.dependentPhaseout.apply(
+		float64(state.dependentExemption), agi) * float64(numDependents)
+	b.dependentExemption = dependentExemption
 	if state.dependentIsCredit {
 		// it's a direct credit. Subtract it from tax.
 		// a negative is okay for now because it gets
 		// checked in the second to last line of the func
 		tax -= dependentExemption
+		b.credits += dependentExemption
+		w.add("dependent credit", -dependentExemption, taxableIncome, tax)
 	} else {
 		taxableIncome -= dependentExemption
+		w.add("dependent exemption", -dependentExemption, taxableIncome, tax)
 	}
 
+	standardDeduction := data.deductionPhaseout.apply(float64(data.standardDeduction), agi)
+	standardDeduction += float64(data.additionalDeduction * (household.numSeniors(65) + household.numBlind()))
+	b.standardDeduction = standardDeduction
 	if state.stdDeductionIsCredit {
-		tax -= float64(data.standardDeduction)
+		tax -= standardDeduction
+		b.credits += standardDeduction
+		w.add("standard deduction, as a credit", -standardDeduction, taxableIncome, tax)
 	} else {
-		taxableIncome -= float64(data.standardDeduction)
+		taxableIncome -= standardDeduction
+		w.add("standard deduction", -standardDeduction, taxableIncome, tax)
 	}
 
+	plan529 := household.contributions529
+	if data.plan529Limit >= 0 {
+		plan529 = math.Min(plan529, float64(data.plan529Limit))
+	}
+	if state.pretax.plan529CreditRate > 0 {
+		tax -= plan529 * state.pretax.plan529CreditRate
+		b.plan529 = plan529
+		b.credits += plan529 * state.pretax.plan529CreditRate
+		w.add("529 contribution credit", -plan529*state.pretax.plan529CreditRate, taxableIncome, tax)
+	} else if data.plan529Limit != 0 {
+		taxableIncome -= plan529
+		b.plan529 = plan529
+		w.add("529 contribution deduction", -plan529, taxableIncome, tax)
+	}
+
+	personalExemption := data.exemptionPhaseout.apply(float64(data.personalExemption), agi)
+	personalExemption += float64(state.senior.exemption*numSeniors + state.senior.blindExemption*household.numBlind())
+	b.personalExemption = personalExemption
 	if state.exemptionIsCredit {
-		tax -= float64(data.personalExemption)
+		tax -= personalExemption
+		b.credits += personalExemption
+		w.add("personal exemption, as a credit", -personalExemption, taxableIncome, tax)
 	} else {
-		taxableIncome -= float64(data.personalExemption)
+		taxableIncome -= personalExemption
+		w.add("personal exemption", -personalExemption, taxableIncome, tax)
 	}
 
 	for i, val := range state.incomeTypesTaxed {
@@ -1316,18 +2106,26 @@ func (state *State) calcIncomeTax(income, capitalGains, dividends *float64,
 			// val is negative indicating a special case
 			switch i {
 			case 0:
-				// it's one of 6 states where federal tax can be deducted from state income
-				taxableIncome -= float64(federalTax)
+				// it's one of 6 states where federal tax can be deducted from state income.
+				// a refundable EITC can push federal tax negative, which isn't added back.
+				federalTax, _ := federal.calcFederalIncomeTax(household)
+				b.federalTaxDeduction = math.Max(0, float64(federalTax))
+				taxableIncome -= b.federalTaxDeduction
+				w.add("federal tax deduction", -b.federalTaxDeduction, taxableIncome, tax)
 			case 1:
-				taxableIncome += (*capitalGains) * (1.0 - float64(val))
+				taxableIncome += capitalGains * (1.0 - float64(val))
+				w.addf(capitalGains*(1.0-float64(val)), taxableIncome, tax,
+					"capital gains, %.0f%% taxed as ordinary income", 100*(1.0-float64(val)))
 			}
 		} else if val == float32(1) {
 			// val is 1, meaning the category is taxed the same as ordinary income
 			switch i {
 			case 1:
-				taxableIncome += (*capitalGains)
+				taxableIncome += capitalGains
+				w.add("capital gains, taxed as ordinary income", capitalGains, taxableIncome, tax)
 			case 2:
-				taxableIncome += (*dividends)
+				taxableIncome += dividends
+				w.add("dividends and interest, taxed as ordinary income", dividends, taxableIncome, tax)
 			}
 		} else {
 			// there's a positive decimal value denoting a multiplier.
@@ -1335,39 +2133,438 @@ func (state *State) calcIncomeTax(income, capitalGains, dividends *float64,
 			switch i {
 			case 1:
 				// we add to `tax`, not `taxableIncome` because these rates are specific
-				tax += (*capitalGains) * float64(val)
+				tax += capitalGains * float64(val)
+				b.specialRateTax += capitalGains * float64(val)
+				w.addRate("capital gains", capitalGains, float64(val), taxableIncome, tax)
 			case 2:
-				tax += (*dividends) * float64(val)
+				tax += dividends * float64(val)
+				b.specialRateTax += dividends * float64(val)
+				w.addRate("dividends and interest", dividends, float64(val), taxableIncome, tax)
 			}
 		}
 	}
-	tax += taxEngine(&taxableIncome, &data.brackets, &data.rates)
+	b.taxableIncome = taxableIncome
+	b.bracketTax = taxEngine(&taxableIncome, &data.brackets, &data.rates)
+	w.addBrackets(taxableIncome, data.brackets, data.rates, tax)
+	tax += b.bracketTax
+	b.recapture = data.calcRecapture(taxableIncome, agi)
+	tax += b.recapture
+	w.add("recapture of the benefit of lower brackets", b.recapture, taxableIncome, tax)
+	b.credits += float64(state.senior.credit * numSeniors)
+	tax -= float64(state.senior.credit * numSeniors)
+	w.add("senior credit", -float64(state.senior.credit*numSeniors), taxableIncome, tax)
+	if tax < 0 {
+		w.add("credits can't take tax below zero", -tax, taxableIncome, 0)
+	}
 	tax = math.Max(0, tax) // assert tax >= 0
-	return int(tax), tax / grossIncome
+	b.eitc = state.calcEITC(federal.calcEITC(household), numDependents, tax)
+	b.tax = tax - b.eitc
+	w.add("earned income tax credit", -b.eitc, taxableIncome, b.tax)
+	return b
+}
+
+// calcTaxableMuniInterest returns the municipal bond interest the state taxes. it's
+// taxed like other interest. treasury interest is never taxed by states.
+func (state *State) calcTaxableMuniInterest(household *Household) float64 {
+	interest := 0.0
+	if state.taxesInStateMunis {
+		interest += household.inStateMuniInterest
+	}
+	if !state.exemptsOtherMunis {
+		interest += household.otherMuniInterest
+	}
+	return interest
 }
 
-func (federal *Federal) calcFederalIncomeTax(
-	income, capitalGains, dividends float64,
-	mfj, qualified bool) (int, float64) {
+// calcWages returns wages after the pre-tax contributions the state excludes.
+func (state *State) calcWages(household *Household) float64 {
+	wages := household.income
+	if !state.pretax.taxes401k {
+		wages -= household.contributions401k
+	}
+	if !state.pretax.taxesHSA {
+		wages -= household.contributionsHSA
+	}
+	if !state.pretax.taxesFSA {
+		wages -= household.contributionsFSA
+	}
+	return wages
+}
+
+// calcRetirementIncome returns the retirement income the state taxes as ordinary income.
+func (state *State) calcRetirementIncome(household *Household, federal *Federal) float64 {
+	provisions := &state.retirement
+	socialSecurity := state.calcTaxableSocialSecurity(household, federal)
+	military := household.militaryPension
+	if provisions.militaryExclusion < 0 {
+		military = 0
+	} else {
+		military = math.Max(0, military-float64(provisions.militaryExclusion))
+	}
+
+	// pool whatever the pension exclusion covers, and exclude from it
+	excludable, other := household.pension, 0.0
+	if provisions.includesIRA {
+		excludable += household.iraDistributions
+	} else {
+		other += household.iraDistributions
+	}
+	if provisions.includesSocialSecurity {
+		excludable += socialSecurity
+	} else {
+		other += socialSecurity
+	}
+	if provisions.pensionExclusion < 0 {
+		excludable = 0
+	} else {
+		numFilers := household.numSeniors(provisions.exclusionAge)
+		if provisions.exclusionAge == 0 {
+			numFilers = household.numFilers()
+		}
+		excludable = math.Max(0, excludable-float64(provisions.pensionExclusion*numFilers))
+	}
+	return excludable + other + military
+}
+
+// calcTaxableSocialSecurity returns the social security benefits the state taxes, before any
+// retirement exclusion. states with a limit only tax them above that much federal AGI.
+func (state *State) calcTaxableSocialSecurity(household *Household, federal *Federal) float64 {
+	data := state.single
+	if household.mfj {
+		data = state.couple
+	}
+	if data.socialSecurityLimit > 0 && federal.calcAGI(household) <= float64(data.socialSecurityLimit) {
+		return 0
+	}
+	return federal.calcTaxableSocialSecurity(household) * state.retirement.socialSecurityTaxed
+}
+
+// calcAGI returns the state's adjusted gross income, which its phase-outs and recaptures are
+// based on. it's federal AGI with the pre-tax contributions, social security benefits and
+// interest the state taxes in place of the federal ones, so exempt income doesn't count.
+func (state *State) calcAGI(household *Household, federal *Federal) float64 {
+	return federal.calcAGI(household) + state.calcWages(household) - household.wages() -
+		federal.calcTaxableSocialSecurity(household) + state.calcTaxableSocialSecurity(household, federal) -
+		household.treasuryInterest + state.calcTaxableMuniInterest(household)
+}
+
+// exclude removes up to `retirementExclusion` per qualifying filer from retirement income, then
+// dividends, then capital gains, then (up to `earnedExclusion` per filer) earned income.
+func (senior *SeniorProvisions) exclude(numSeniors int,
+	retirement, income, capitalGains, dividends float64) (float64, float64, float64, float64) {
+	remaining := float64(senior.retirementExclusion * numSeniors)
+	take := func(amount, limit float64) float64 {
+		excluded := math.Min(math.Max(0, amount), math.Min(remaining, limit))
+		remaining -= excluded
+		return amount - excluded
+	}
+	retirement = take(retirement, remaining)
+	dividends = take(dividends, remaining)
+	capitalGains = take(capitalGains, remaining)
+	income = take(income, float64(senior.earnedExclusion*numSeniors))
+	return retirement, income, capitalGains, dividends
+}
+
+// retirementIncome returns the household's gross retirement income.
+func (household *Household) retirementIncome() float64 {
+	return household.socialSecurity + household.pension + household.militaryPension + household.iraDistributions
+}
+
+// muniInterest returns the household's municipal bond interest, which is federally exempt.
+func (household *Household) muniInterest() float64 {
+	return household.inStateMuniInterest + household.otherMuniInterest
+}
+
+// specialInterest returns the household's treasury and municipal bond interest.
+func (household *Household) specialInterest() float64 {
+	return household.treasuryInterest + household.muniInterest()
+}
+
+// grossIncome returns all of the household's income, including all social security benefits
+// and tax-exempt interest.
+func (household *Household) grossIncome() float64 {
+	return household.income + household.capitalGains + household.dividends + household.ordinaryInterest +
+		household.retirementIncome() + household.specialInterest()
+}
+
+// pretaxContributions returns the contributions excluded from federal wages.
+func (household *Household) pretaxContributions() float64 {
+	return household.contributions401k + household.contributionsHSA + household.contributionsFSA
+}
+
+// wages returns federal taxable wages, i.e. `income` less pre-tax contributions.
+func (household *Household) wages() float64 {
+	return household.income - household.pretaxContributions()
+}
+
+// payrollWages returns the wages subject to payroll taxes: `income` including 401(k) deferrals
+// but not cafeteria plan HSA and FSA contributions. dividends and interest aren't wages.
+func (household *Household) payrollWages() float64 {
+	return household.income - household.contributionsHSA - household.contributionsFSA
+}
+
+// earnerWages splits payrollWages between the filer and the spouse, who earned `spouseIncome`
+// of it. HSA and FSA contributions come out of the filer's wages first.
+func (household *Household) earnerWages() [2]float64 {
+	wages, spouse := household.payrollWages(), household.spouseIncome
+	if wages < spouse {
+		spouse = math.Max(0, wages)
+	}
+	return [2]float64{wages - spouse, spouse}
+}
+
+// scaled returns a copy of the household with every amount multiplied by `share`.
+func (household *Household) scaled(share float64) *Household {
+	scaled := *household
+	for _, amount := range []*float64{
+		&scaled.income, &scaled.capitalGains, &scaled.dividends, &scaled.ordinaryInterest,
+		&scaled.socialSecurity, &scaled.pension, &scaled.militaryPension, &scaled.iraDistributions,
+		&scaled.contributions401k, &scaled.contributionsHSA, &scaled.contributionsFSA, &scaled.contributions529,
+		&scaled.treasuryInterest, &scaled.inStateMuniInterest, &scaled.otherMuniInterest, &scaled.spouseIncome,
+	} {
+		*amount *= share
+	}
+	return &scaled
+}
+
+// sweepStep returns the household scaled to the `i`th of `numSteps` even steps up to its full
+// size. contributions and retirement income scale with income, so wages never go negative.
+func (household *Household) sweepStep(i, numSteps int) *Household {
+	return household.scaled(float64(i+1) / float64(numSteps))
+}
+
+// numFilers returns 2 for a joint return, otherwise 1.
+func (household *Household) numFilers() int {
+	if household.mfj {
+		return 2
+	}
+	return 1
+}
+
+// numSeniors returns how many of the filers are `age` or older. 0 means no age provisions.
+func (household *Household) numSeniors(age int) int {
+	if age == 0 {
+		return 0
+	}
+	count := 0
+	if household.age >= age {
+		count++
+	}
+	if household.mfj && household.spouseAge >= age {
+		count++
+	}
+	return count
+}
+
+// numBlind returns how many of the filers are blind.
+func (household *Household) numBlind() int {
+	count := 0
+	if household.blind {
+		count++
+	}
+	if household.mfj && household.spouseBlind {
+		count++
+	}
+	return count
+}
+
+// calcRecapture returns the tax added by the filing status' benefit recapture rules.
+func (data *FilingStatus) calcRecapture(taxableIncome, agi float64) float64 {
 	tax := 0.0
+	for _, rule := range data.recaptures {
+		if agi <= float64(rule.start) {
+			continue
+		}
+		switch rule.kind {
+		case addBack:
+			steps := math.Ceil((agi - float64(rule.start)) / float64(rule.step))
+			tax += math.Min(rule.max, steps*rule.amount)
+		case flatRate:
+			tax += data.calcFlatRateRecapture(taxableIncome, agi, rule)
+		}
+	}
+	return tax
+}
+
+func (data *FilingStatus) calcFlatRateRecapture(taxableIncome, agi float64, rule Recapture) float64 {
+	// find the bracket that taxable income falls in
+	k := 0
+	for i, bracket := range data.brackets {
+		if taxableIncome > float64(bracket) {
+			k = i
+		}
+	}
+	if k == 0 {
+		// only the lowest rate applies, there is no benefit to recapture
+		return 0
+	}
+	threshold, rate := float64(data.brackets[k]), data.rates[k]
+	ordinary := taxEngine(&taxableIncome, &data.brackets, &data.rates)
+	lower, phaseInStart := ordinary, float64(rule.start)
+	if threshold > phaseInStart {
+		// the benefit below this bracket was already recaptured at the previous bracket's rate
+		lower = data.rates[k-1]*threshold + rate*(taxableIncome-threshold)
+		phaseInStart = threshold
+	}
+	fraction := math.Max(0, math.Min(1, (agi-phaseInStart)/float64(rule.step)))
+	return lower - ordinary + (rate*taxableIncome-lower)*fraction
+}
+
+// apply returns `amount` after the phase-out for the given AGI, never below 0.
+func (phaseout *Phaseout) apply(amount, agi float64) float64 {
+	if phaseout.reduction == 0 || agi <= float64(phaseout.start) {
+		return amount
+	}
+	if phaseout.end > 0 {
+		agi = math.Min(agi, float64(phaseout.end))
+	}
+	steps := agi - float64(phaseout.start)
+	if phaseout.step > 0 {
+		steps = math.Ceil(steps / float64(phaseout.step))
+	}
+	return math.Max(0, amount-steps*phaseout.reduction)
+}
+
+// calcEITC returns the state's EITC as a percentage of the federal credit.
+// A non-refundable credit is limited to `tax`, the liability before the credit.
+func (state *State) calcEITC(federalEITC float64, numDependents int, tax float64) float64 {
+	if len(state.eitc.rates) == 0 {
+		return 0
+	}
+	rate := state.eitc.rates[int(math.Min(float64(numDependents), float64(len(state.eitc.rates)-1)))]
+	credit := federalEITC * rate
+	if !state.eitc.refundable {
+		credit = math.Min(credit, tax)
+	}
+	return credit
+}
+
+func (federal *Federal) calcFederalIncomeTax(household *Household) (int, float64) {
+	breakdown := federal.calcBreakdown(household)
+	return int(breakdown.tax), breakdown.tax / breakdown.grossIncome
+}
+func (federal *Federal) calcBreakdown(household *Household) *FederalBreakdown {
+	income, capitalGains, dividends := household.wages(), household.capitalGains, household.dividends
+	// retirement income and treasury interest are taxed as ordinary income; muni interest isn't taxed
+	retirement := household.pension + household.militaryPension + household.iraDistributions +
+		federal.calcTaxableSocialSecurity(household) + household.treasuryInterest
 	data := federal.single
-	if mfj {
+	if household.mfj {
 		data = federal.couple
 	}
-	if qualified {
+	if household.qualified {
 		capitalGains += dividends
 	} else {
 		income += dividends
 	}
-	grossIncome := income + capitalGains + dividends
-	income -= float64(data.standardDeduction)
-	income = math.Max(0.0, income)
-	tax += income * federal.medicareRate
-	tax += taxEngine(&income, &data.incomeBrackets, &data.incomeRates)
-	tax += taxEngine(&capitalGains, &data.capitalGainsBrackets, &data.capitalGainsRates)
-	ssCappedIncome := math.Min(float64(federal.socialSecurityCap), income)
-	tax += ssCappedIncome * federal.socialSecurityRate
-	return int(tax), tax / grossIncome
+	income += household.ordinaryInterest
+	b := &FederalBreakdown{
+		// capitalGains already includes qualified dividends
+		grossIncome:    household.grossIncome(),
+		ordinaryIncome: income + retirement,
+		deductions:     data.calcDeductions(household),
+		capitalGains:   capitalGains,
+		eitc:           federal.calcEITC(household),
+	}
+	// retirement income is taxed as ordinary income but isn't subject to payroll taxes
+	b.taxableIncome = math.Max(0.0, b.ordinaryIncome-b.deductions)
+	b.payrollTax = federal.calcPayrollTax(household)
+	b.incomeTax = taxEngine(&b.taxableIncome, &data.incomeBrackets, &data.incomeRates)
+	b.capitalGainsTax = taxEngine(&capitalGains, &data.capitalGainsBrackets, &data.capitalGainsRates)
+	// the EITC is fully refundable, so tax can go negative
+	b.tax = b.payrollTax + b.incomeTax + b.capitalGainsTax - b.eitc
+	return b
+}
+
+// calcPayrollTax returns the medicare and social security taxes included in calcFederalIncomeTax,
+// the employee's share. they only apply to wages, including 401(k) deferrals. deductions don't
+// reduce them, so they don't depend on age or blindness. social security is capped per earner.
+func (federal *Federal) calcPayrollTax(household *Household) float64 {
+	socialSecurity := 0.0
+	for _, wages := range household.earnerWages() {
+		socialSecurity += federal.calcSocialSecurityTax(wages)
+	}
+	return socialSecurity + federal.calcMedicareTax(household.payrollWages(), 0) +
+		federal.calcAdditionalMedicareTax(household)
+}
+
+// calcAdditionalMedicareTax returns the additional medicare tax on the filers' combined wages
+func (federal *Federal) calcAdditionalMedicareTax(household *Household) float64 {
+	data := federal.single
+	if household.mfj {
+		data = federal.couple
+	}
+	return math.Max(0, household.payrollWages()-float64(data.additionalMedicareThreshold)) * federal.additionalMedicareRate
+}
+
+// calcSocialSecurityTax returns one earner's social security tax on their wages
+func (federal *Federal) calcSocialSecurityTax(wages float64) float64 {
+	return math.Min(float64(federal.socialSecurityCap), math.Max(0, wages)) * federal.socialSecurityRate
+}
+
+// calcMedicareTax returns the medicare tax on `wages`, with the additional medicare tax over
+// `threshold` if it isn't 0
+func (federal *Federal) calcMedicareTax(wages, threshold float64) float64 {
+	wages = math.Max(0, wages)
+	tax := wages * federal.medicareRate
+	if threshold > 0 {
+		tax += math.Max(0, wages-threshold) * federal.additionalMedicareRate
+	}
+	return tax
+}
+
+// calcDeductions returns the standard deduction, including the additional deduction for age and blindness.
+func (data *FedFilingStatus) calcDeductions(household *Household) float64 {
+	return float64(data.standardDeduction + data.additionalDeduction*(household.numSeniors(65)+household.numBlind()))
+}
+
+// calcAGI returns adjusted gross income, which only includes the taxable part of social security.
+func (federal *Federal) calcAGI(household *Household) float64 {
+	return household.grossIncome() - household.pretaxContributions() - household.muniInterest() -
+		household.socialSecurity + federal.calcTaxableSocialSecurity(household)
+}
+
+// calcTaxableSocialSecurity returns the part of social security benefits subject to
+// federal income tax: none, up to 50%, or up to 85%, depending on provisional income.
+func (federal *Federal) calcTaxableSocialSecurity(household *Household) float64 {
+	data := federal.single
+	if household.mfj {
+		data = federal.couple
+	}
+	benefits := household.socialSecurity
+	if benefits <= 0 {
+		return 0
+	}
+	provisional := household.grossIncome() - household.pretaxContributions() - benefits + 0.5*benefits
+	base, adjustedBase := float64(data.socialSecurityBases[0]), float64(data.socialSecurityBases[1])
+	if provisional <= base {
+		return 0
+	}
+	if provisional <= adjustedBase {
+		return math.Min(0.5*benefits, 0.5*(provisional-base))
+	}
+	return math.Min(0.85*benefits, 0.85*(provisional-adjustedBase)+math.Min(0.5*benefits, 0.5*(adjustedBase-base)))
+}
+
+// calcEITC returns the federal Earned Income Tax Credit. All dependents are
+// treated as qualifying children and `income` as earned income.
+func (federal *Federal) calcEITC(household *Household) float64 {
+	income, capitalGains, dividends := household.wages(), household.capitalGains, household.dividends
+	numDependents := household.numDependents
+	data := federal.single
+	if household.mfj {
+		data = federal.couple
+	}
+	if capitalGains+dividends+household.ordinaryInterest+household.specialInterest() > float64(federal.eitcInvestmentCap) {
+		return 0
+	}
+	schedule := data.eitc[int(math.Min(float64(numDependents), float64(len(data.eitc)-1)))]
+	maxCredit := schedule.creditRate * float64(schedule.earnedAmount)
+	credit := math.Min(maxCredit, schedule.creditRate*math.Max(0, income))
+	// the phase-out uses the greater of AGI and earned income
+	agi := math.Max(income, federal.calcAGI(household))
+	credit -= schedule.phaseoutRate * math.Max(0, agi-float64(schedule.phaseoutStart))
+	return math.Max(0, credit)
 }
 
 func taxEngine(income *float64, brackets *[]int, rates *[]float64) float64 {
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// within returns whether got is within a cent of want
+func within(got, want float64) bool {
+	return math.Abs(got-want) < 0.01
+}
+
+func TestFederalEITC(t *testing.T) {
+	tests := []struct {
+		name      string
+		household Household
+		want      float64
+	}{
+		{"no children, phasing in", Household{income: 5000}, 382.50},
+		{"no children, maximum", Household{income: 9000}, 559.98},
+		{"no children, phasing out", Household{income: 12000}, 559.98 - 0.0765*(12000-9160)},
+		{"no children, phased out", Household{income: 17000}, 0},
+		{"one child, maximum", Household{income: 10980, numDependents: 1}, 3733.20},
+		{"two children, phasing out", Household{income: 30000, numDependents: 2}, 6164 - 0.2106*(30000-20130)},
+		{"three children, maximum", Household{income: 16000, numDependents: 3}, 6934.50},
+		{"more than three children", Household{income: 16000, numDependents: 5}, 6934.50},
+		{"joint, later phase-out", Household{income: 25000, mfj: true, numDependents: 2}, 6164},
+		{"joint, phasing out", Household{income: 40000, mfj: true, numDependents: 1}, 3733.20 - 0.1598*(40000-26260)},
+		{"phases out on AGI", Household{income: 15000, dividends: 8000, numDependents: 1}, 3733.20 - 0.1598*(23000-20130)},
+		{"investment income over the cap", Household{income: 10000, dividends: 10301, numDependents: 1}, 0},
+		{"pre-tax contributions aren't earned income", Household{income: 6000, contributions401k: 1000}, 382.50},
+		{"no income", Household{numDependents: 2}, 0},
+	}
+	for _, test := range tests {
+		federal := initializeFederal(&test.household)
+		if got := federal.calcEITC(&test.household); !within(got, math.Max(0, test.want)) {
+			t.Errorf("%s: calcEITC() = %.2f, want %.2f", test.name, got, math.Max(0, test.want))
+		}
+	}
+}
+
+func TestStateEITC(t *testing.T) {
+	refundable := &State{eitc: StateEITC{rates: []float64{0.30}, refundable: true}}
+	nonrefundable := &State{eitc: StateEITC{rates: []float64{0.30}, refundable: false}}
+	byChildren := &State{eitc: StateEITC{rates: []float64{0.0, 0.04, 0.11, 0.34}, refundable: true}}
+	tests := []struct {
+		name          string
+		state         *State
+		federalEITC   float64
+		numDependents int
+		tax           float64
+		want          float64
+	}{
+		{"no state EITC", &State{}, 1000, 1, 500, 0},
+		{"refundable", refundable, 1000, 1, 0, 300},
+		{"non-refundable, limited to tax", nonrefundable, 1000, 1, 120, 120},
+		{"non-refundable, under tax", nonrefundable, 1000, 1, 500, 300},
+		{"rate by children", byChildren, 1000, 2, 0, 110},
+		{"last rate for more children", byChildren, 1000, 4, 0, 340},
+		{"no children", byChildren, 500, 0, 0, 0},
+	}
+	for _, test := range tests {
+		if got := test.state.calcEITC(test.federalEITC, test.numDependents, test.tax); !within(got, test.want) {
+			t.Errorf("%s: calcEITC() = %.2f, want %.2f", test.name, got, test.want)
+		}
+	}
+}
+
+func TestStateEITCData(t *testing.T) {
+	states := testStates()
+	tests := []struct {
+		abbrev        string
+		federalEITC   float64
+		numDependents int
+		want          float64
+	}{
+		// with no tax to offset, only refundable credits are paid
+		{"HI", 3400, 1, 680},
+		{"CT", 3400, 1, 1411},
+		{"DC", 3400, 1, 2380},
+		{"DC", 500, 0, 500},
+		{"DE", 3400, 1, 0},
+	}
+	for _, test := range tests {
+		state := findState(states, test.abbrev)
+		if got := state.calcEITC(test.federalEITC, test.numDependents, 0); !within(got, test.want) {
+			t.Errorf("%s with %d children: calcEITC(%.0f) = %.2f, want %.2f",
+				test.abbrev, test.numDependents, test.federalEITC, got, test.want)
+		}
+	}
+}
+
+func TestPhaseoutApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		phaseout Phaseout
+		amount   float64
+		agi      float64
+		want     float64
+	}{
+		{"no phase-out", Phaseout{}, 1000, 1e6, 1000},
+		{"at the start", Phaseout{start: 50000, step: 2500, reduction: 20}, 1000, 50000, 1000},
+		{"part of a step", Phaseout{start: 50000, step: 2500, reduction: 20}, 1000, 50001, 980},
+		{"whole steps", Phaseout{start: 50000, step: 2500, reduction: 20}, 1000, 55000, 960},
+		{"continuous", Phaseout{start: 50000, reduction: 0.1}, 1000, 52500, 750},
+		{"never below zero", Phaseout{start: 50000, reduction: 0.1}, 1000, 100000, 0},
+		{"stops at the end", Phaseout{start: 50000, end: 60000, step: 1000, reduction: 10}, 1000, 90000, 900},
+		{"before the end", Phaseout{start: 50000, end: 60000, step: 1000, reduction: 10}, 1000, 54500, 950},
+	}
+	for _, test := range tests {
+		if got := test.phaseout.apply(test.amount, test.agi); !within(got, test.want) {
+			t.Errorf("%s: apply(%.0f, %.0f) = %.2f, want %.2f", test.name, test.amount, test.agi, got, test.want)
+		}
+	}
+}
+
+// testStates returns the states for a household with no income, for tests of their data
+func testStates() *[51]*State {
+	household := &Household{}
+	return initializeStates(household, initializeFederal(household))
+}
+
+func TestRecapture(t *testing.T) {
+	states := testStates()
+	ct, ny := findState(states, "CT"), findState(states, "NY")
+	tests := []struct {
+		name          string
+		data          *FilingStatus
+		taxableIncome float64
+		agi           float64
+		want          float64
+	}{
+		{"CT at the start of the 3% phase-out", &ct.single, 56500, 56500, 0},
+		{"CT part of the first step", &ct.single, 56501, 56501, 20},
+		{"CT end of the first step", &ct.single, 61500, 61500, 20},
+		{"CT start of the second step", &ct.single, 61501, 61501, 40},
+		{"CT 3% phase-out complete", &ct.single, 200000, 200000, 200},
+		{"CT benefit recapture starts", &ct.single, 200001, 200001, 275},
+		{"CT everything recaptured", &ct.single, 1e6, 1e6, 200 + 2250 + 950},
+		{"CT joint at the start", &ct.couple, 100500, 100500, 0},
+		{"CT joint first step", &ct.couple, 100501, 100501, 40},
+		{"CT joint benefit recapture", &ct.couple, 410000, 410000, 400 + 150},
+		{"NY in the lowest bracket", &ny.single, 8000, 200000, 0},
+		{"NY at the start of the phase-in", &ny.single, 100000, 107650, 0},
+		{"NY halfway through the phase-in", &ny.single, 100000, 132650, 268.125},
+		{"NY at the end of the phase-in", &ny.single, 100000, 157650, 536.25},
+		{"NY past the phase-in", &ny.single, 100000, 200000, 536.25},
+		{"NY halfway into the next bracket's phase-in", &ny.single, 240400, 240400, 536.25 + 646.2},
+		{"NY next bracket fully recaptured", &ny.single, 300000, 300000, 536.25 + 1292.4},
+		{"NY joint at the start of the phase-in", &ny.couple, 100000, 107650, 0},
+	}
+	for _, test := range tests {
+		if got := test.data.calcRecapture(test.taxableIncome, test.agi); !within(got, test.want) {
+			t.Errorf("%s: calcRecapture(%.0f, %.0f) = %.2f, want %.2f",
+				test.name, test.taxableIncome, test.agi, got, test.want)
+		}
+	}
+
+	// once fully phased in, NY's supplemental tax leaves all income taxed at the top rate reached
+	for _, taxableIncome := range []float64{100000, 300000, 1.2e6, 6e6, 3e7} {
+		data := &ny.single
+		agi := taxableIncome + 1e6
+		rate := data.rates[0]
+		for i, bracket := range data.brackets {
+			if taxableIncome > float64(bracket) {
+				rate = data.rates[i]
+			}
+		}
+		got := taxEngine(&taxableIncome, &data.brackets, &data.rates) + data.calcRecapture(taxableIncome, agi)
+		if !within(got, rate*taxableIncome) {
+			t.Errorf("NY flat rate at %.0f: tax = %.2f, want %.2f", taxableIncome, got, rate*taxableIncome)
+		}
+	}
+}
+
+func TestPayrollTaxIgnoresDeductions(t *testing.T) {
+	tests := []struct {
+		name      string
+		household Household
+		want      float64
+	}{
+		{"single", Household{income: 100000}, 7650},
+		{"joint", Household{income: 100000, mfj: true}, 7650},
+		{"65 and blind", Household{income: 100000, age: 67, blind: true}, 7650},
+		{"joint, both 65 and blind", Household{income: 100000, mfj: true, age: 70, spouseAge: 68,
+			blind: true, spouseBlind: true}, 7650},
+		{"under the standard deduction", Household{income: 10000}, 765},
+		{"over the social security cap", Household{income: 200000}, 200000*0.0145 + 147000*0.062},
+	}
+	for _, test := range tests {
+		federal := initializeFederal(&test.household)
+		if got := federal.calcPayrollTax(&test.household); !within(got, test.want) {
+			t.Errorf("%s: calcPayrollTax() = %.2f, want %.2f", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPayrollTaxPerEarner(t *testing.T) {
+	tests := []struct {
+		name      string
+		household Household
+		want      float64
+	}{
+		{"single, additional medicare over $200,000", Household{income: 250000}, 9114 + 3625 + 450},
+		{"joint, one earner", Household{income: 300000, mfj: true}, 9114 + 4350 + 450},
+		{"joint, two earners each under the cap", Household{income: 300000, mfj: true, spouseIncome: 150000},
+			2*9114 + 4350 + 450},
+		{"joint, one earner over the cap", Household{income: 200000, mfj: true, spouseIncome: 50000},
+			9114 + 50000*0.062 + 2900},
+		{"joint, under $250,000 together", Household{income: 240000, mfj: true, spouseIncome: 120000},
+			240000 * 0.0765},
+		{"HSA comes out of the filer's wages", Household{income: 200000, mfj: true, spouseIncome: 100000,
+			contributionsHSA: 5000}, 195000 * 0.0765},
+	}
+	for _, test := range tests {
+		federal := initializeFederal(&test.household)
+		if got := federal.calcPayrollTax(&test.household); !within(got, test.want) {
+			t.Errorf("%s: calcPayrollTax() = %.2f, want %.2f", test.name, got, test.want)
+		}
+	}
+}
+
+func TestStatePhaseoutsUseStateAGI(t *testing.T) {
+	states := testStates()
+	wi := findState(states, "WI")
+	wages := &Household{income: 60000, age: 67}
+	withBenefits := &Household{income: 60000, age: 67, socialSecurity: 30000}
+	federal := initializeFederal(withBenefits)
+	want := wi.calcBreakdown(wages, federal, nil).standardDeduction
+	if got := wi.calcBreakdown(withBenefits, federal, nil).standardDeduction; !within(got, want) {
+		t.Errorf("WI standard deduction with untaxed social security = %.2f, want %.2f", got, want)
+	}
+}
+
+func TestSocialSecurityLimitUsesAGI(t *testing.T) {
+	ct := findState(testStates(), "CT")
+	tests := []struct {
+		name      string
+		household Household
+		taxed     bool
+	}{
+		// $48,000 of wages and $30,000 of benefits is $73,500 of AGI, under CT's $75,000 limit
+		{"gross over the limit, AGI under", Household{income: 48000, socialSecurity: 30000}, false},
+		{"AGI over the limit", Household{income: 60000, socialSecurity: 30000}, true},
+		{"joint, AGI under the limit", Household{income: 70000, socialSecurity: 30000, mfj: true}, false},
+	}
+	for _, test := range tests {
+		federal := initializeFederal(&test.household)
+		if got := ct.calcTaxableSocialSecurity(&test.household, federal); (got > 0) != test.taxed {
+			t.Errorf("%s: CT taxable social security = %.2f, want taxed %t", test.name, got, test.taxed)
+		}
+	}
+}
+
+func TestPayrollTaxOnWages(t *testing.T) {
+	tests := []struct {
+		name      string
+		household Household
+		want      float64
+	}{
+		{"nonqualified dividends aren't wages", Household{income: 50000, dividends: 20000}, 3825},
+		{"qualified dividends aren't wages", Household{income: 50000, dividends: 20000, qualified: true}, 3825},
+		{"nor are gains, interest or retirement income", Household{income: 50000, capitalGains: 10000,
+			treasuryInterest: 1000, pension: 5000, iraDistributions: 5000}, 3825},
+		{"401(k) deferrals are wages", Household{income: 50000, contributions401k: 10000}, 3825},
+		{"HSA and FSA contributions aren't", Household{income: 50000, contributionsHSA: 3000,
+			contributionsFSA: 2000}, 45000 * 0.0765},
+	}
+	for _, test := range tests {
+		federal := initializeFederal(&test.household)
+		if got := federal.calcPayrollTax(&test.household); !within(got, test.want) {
+			t.Errorf("%s: calcPayrollTax() = %.2f, want %.2f", test.name, got, test.want)
+		}
+	}
+}
+
+func TestExemptInterestLeavesPhaseoutsUnchanged(t *testing.T) {
+	for _, income := range []float64{40000, 60000, 120000, 250000, 600000} {
+		for _, mfj := range []bool{false, true} {
+			wages := &Household{income: income, mfj: mfj, numDependents: 2}
+			withInterest := *wages
+			withInterest.treasuryInterest, withInterest.inStateMuniInterest = 15000, 20000
+			federal := initializeFederal(wages)
+			for _, state := range initializeStates(wages, federal) {
+				if state.taxesInStateMunis {
+					continue
+				}
+				want := state.calcBreakdown(wages, federal, nil)
+				got := state.calcBreakdown(&withInterest, federal, nil)
+				if !within(got.dependentExemption, want.dependentExemption) ||
+					!within(got.standardDeduction, want.standardDeduction) ||
+					!within(got.personalExemption, want.personalExemption) ||
+					!within(got.recapture, want.recapture) {
+					t.Errorf("%s at %.0f (joint %t): exempt interest changed the phase-outs from %+v to %+v",
+						state.abbrev, income, mfj, *want, *got)
+				}
+			}
+		}
+	}
+}
+
+func TestFederalGrossIncome(t *testing.T) {
+	tests := []struct {
+		name      string
+		household Household
+	}{
+		{"qualified dividends", Household{income: 50000, dividends: 10000, qualified: true}},
+		{"nonqualified dividends", Household{income: 50000, dividends: 10000}},
+		{"gains and qualified dividends", Household{income: 50000, capitalGains: 5000, dividends: 10000,
+			qualified: true}},
+		{"everything", Household{income: 80000, capitalGains: 5000, dividends: 2000, qualified: true,
+			ordinaryInterest: 1000, pension: 12000, socialSecurity: 20000, treasuryInterest: 500,
+			otherMuniInterest: 700, contributions401k: 6000}},
+	}
+	for _, test := range tests {
+		federal := initializeFederal(&test.household)
+		if got, want := federal.calcBreakdown(&test.household).grossIncome, test.household.grossIncome(); !within(got, want) {
+			t.Errorf("%s: federal gross income = %.2f, want %.2f", test.name, got, want)
+		}
+	}
+
+	// $50,000 of wages and $10,000 of qualified dividends: the rate is over $60,000
+	household := &Household{income: 50000, dividends: 10000, qualified: true}
+	federal := initializeFederal(household)
+	b := federal.calcBreakdown(household)
+	if _, rate := federal.calcFederalIncomeTax(household); !within(rate, b.tax/60000) {
+		t.Errorf("federal effective rate = %.4f, want %.4f", rate, b.tax/60000)
+	}
+}
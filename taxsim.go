@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// soiStates maps the TAXSIM (IRS Statistics of Income) state codes to abbreviations.
+// 0 means no state tax is calculated.
+var soiStates = [...]string{"",
+	"AL", "AK", "AZ", "AR", "CA", "CO", "CT", "DE", "DC", "FL", "GA", "HI", "ID", "IL", "IN", "IA", "KS",
+	"KY", "LA", "ME", "MD", "MA", "MI", "MN", "MS", "MO", "MT", "NE", "NV", "NH", "NJ", "NM", "NY", "NC",
+	"ND", "OH", "OK", "OR", "PA", "RI", "SC", "SD", "TN", "TX", "UT", "VT", "VA", "WA", "WV", "WI", "WY"}
+
+// the TAXSIM 35 output columns that taxify writes
+var taxsimOutput = []string{"taxsimid", "year", "state", "fiitax", "siitax", "fica", "frate", "srate", "ficar", "tfica"}
+
+// taxsimInputs are the TAXSIM inputs taxify calculates with
+var taxsimInputs = map[string]bool{
+	"taxsimid": true, "year": true, "state": true, "mstat": true, "page": true, "sage": true, "depx": true,
+	"pwages": true, "swages": true, "dividends": true, "intrec": true, "ltcg": true, "pensions": true, "gssi": true,
+}
+
+// taxsimIgnored are TAXSIM inputs that only refine provisions taxify doesn't model (the
+// child tax credit's age tests, the output detail level), so they're accepted and ignored
+var taxsimIgnored = map[string]bool{
+	"dep13": true, "dep17": true, "dep18": true, "age1": true, "age2": true, "age3": true, "idtl": true, "mtr": true,
+}
+
+// taxsimUnsupported are TAXSIM inputs taxify has nothing to put in. a row with any of
+// them nonzero is an error rather than a silently wrong answer.
+var taxsimUnsupported = map[string]bool{
+	"psemp": true, "ssemp": true, "stcg": true, "otherprop": true, "nonprop": true, "pui": true,
+	"sui": true, "transfers": true, "rentpaid": true, "proptax": true, "otheritem": true,
+	"childcare": true, "mortgage": true, "scorp": true, "pbusinc": true, "pprofinc": true,
+	"sbusinc": true, "sprofinc": true,
+}
+
+type TaxsimRecord struct {
+	id        string
+	year      string
+	stateCode string // as given, for the output
+	state     *State // nil for state code 0
+	household *Household
+}
+
+type TaxsimResult struct {
+	fiitax float64 // federal income tax, after credits
+	siitax float64
+	fica   float64 // employee and employer shares
+	tfica  float64 // the employee's share
+	frate  float64 // marginal rates on wages, in percent
+	srate  float64
+	ficar  float64
+}
+
+func runTaxsim(args []string) {
+	fs := flag.NewFlagSet("taxsim", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: taxify taxsim [file.csv]\nReads TAXSIM 35 input from stdin without a file and writes TAXSIM output to stdout.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		usageError("taxsim: takes at most one input file, got %v", fs.Args())
+	}
+	input := io.Reader(os.Stdin)
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			usageError("taxsim: %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	household := &Household{}
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	records := readTaxsim(input, states)
+
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(taxsimOutput); err != nil {
+		panic(err)
+	}
+	for _, record := range records {
+		result := calcTaxsim(record, federal)
+		row := []string{record.id, record.year, record.stateCode}
+		for _, value := range []float64{result.fiitax, result.siitax, result.fica,
+			result.frate, result.srate, result.ficar, result.tfica} {
+			row = append(row, strconv.FormatFloat(value, 'f', 2, 64))
+		}
+		if err := w.Write(row); err != nil {
+			panic(err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		panic(err)
+	}
+}
+
+// readTaxsim reads a TAXSIM 35 input CSV. columns can be in any order and missing ones are 0,
+// as in TAXSIM. wages of both spouses are combined for income tax, dividends are qualified
+// and interest is ordinary income.
+func readTaxsim(input io.Reader, states *[51]*State) []*TaxsimRecord {
+	r := csv.NewReader(input)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		usageError("taxsim: reading the header: %v", err)
+	}
+	for i, name := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(name))
+		if !taxsimInputs[header[i]] && !taxsimIgnored[header[i]] && !taxsimUnsupported[header[i]] {
+			usageError("taxsim: %q isn't a TAXSIM 35 input column", name)
+		}
+	}
+
+	var records []*TaxsimRecord
+	for line := 2; ; line++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			usageError("taxsim: %v", err)
+		}
+		values := make(map[string]float64, len(header))
+		record := &TaxsimRecord{household: &Household{}}
+		for i, name := range header {
+			field := strings.TrimSpace(row[i])
+			switch name {
+			case "taxsimid":
+				record.id = field
+				continue
+			case "year":
+				record.year = field
+			case "state":
+				record.stateCode = field
+				if field != "" && (field[0] < '0' || field[0] > '9') {
+					// TAXSIM also takes postal abbreviations
+					if record.state = findState(states, field); record.state == nil {
+						usageError("taxsim: line %d: unknown state %q", line, field)
+					}
+					continue
+				}
+			}
+			if field == "" || field == "." {
+				continue
+			}
+			value, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				usageError("taxsim: line %d: %s must be a number, got %q", line, name, field)
+			}
+			if taxsimUnsupported[name] && value != 0 {
+				usageError("taxsim: line %d: taxify doesn't model %s, so it must be 0", line, name)
+			}
+			values[name] = value
+		}
+		if err := fillTaxsimRecord(record, values, states); err != nil {
+			usageError("taxsim: line %d: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// fillTaxsimRecord fills in the household and state from a row's numeric columns
+func fillTaxsimRecord(record *TaxsimRecord, values map[string]float64, states *[51]*State) error {
+	if year := int(values["year"]); year != taxYear {
+		return fmt.Errorf("year must be %d, the only year taxify has law for, got %d", taxYear, year)
+	}
+	if record.state == nil {
+		code := values["state"]
+		if code < 0 || int(code) >= len(soiStates) || code != float64(int(code)) {
+			return fmt.Errorf("state must be an SOI code from 0 to %d, got %g", len(soiStates)-1, code)
+		}
+		if code > 0 {
+			record.state = findState(states, soiStates[int(code)])
+		}
+	}
+
+	household := record.household
+	switch mstat := values["mstat"]; mstat {
+	case 1:
+		// TAXSIM files single returns with dependents as head of household
+		if values["depx"] > 0 {
+			return fmt.Errorf("mstat 1 with dependents is head of household, which taxify doesn't model")
+		}
+	case 2:
+		household.mfj = true
+	case 6, 8:
+		return fmt.Errorf("mstat %g (married filing separately or a dependent taxpayer) isn't modeled, only 1 and 2", mstat)
+	default:
+		return fmt.Errorf("mstat must be 1 (single) or 2 (joint), got %g", mstat)
+	}
+	household.age, household.spouseAge = int(values["page"]), int(values["sage"])
+	household.numDependents = int(values["depx"])
+	household.income = values["pwages"] + values["swages"]
+	household.spouseIncome = values["swages"]
+	household.dividends, household.qualified = values["dividends"], true
+	household.ordinaryInterest = values["intrec"]
+	household.capitalGains = values["ltcg"]
+	household.pension = values["pensions"]
+	household.socialSecurity = values["gssi"]
+	if household.ordinaryInterest < 0 {
+		return fmt.Errorf("intrec must not be negative, got %g", household.ordinaryInterest)
+	}
+	if values["pwages"] < 0 || values["swages"] < 0 {
+		return fmt.Errorf("pwages and swages must not be negative, got %g and %g", values["pwages"], values["swages"])
+	}
+	if household.spouseIncome > 0 && !household.mfj {
+		return fmt.Errorf("swages must be 0 unless mstat is 2 (joint), got %g", household.spouseIncome)
+	}
+	return (&HouseholdInput{
+		Income:         household.income,
+		CapitalGains:   household.capitalGains,
+		Dividends:      household.dividends,
+		Dependents:     household.numDependents,
+		Age:            household.age,
+		SpouseAge:      household.spouseAge,
+		SocialSecurity: household.socialSecurity,
+		Pension:        household.pension,
+		Joint:          household.mfj,
+		SpouseIncome:   household.spouseIncome,
+	}).validate()
+}
+
+// calcTaxsim calculates a row. marginal rates are on the primary taxpayer's wages, TAXSIM's default.
+func calcTaxsim(record *TaxsimRecord, federal *Federal) *TaxsimResult {
+	result := &TaxsimResult{}
+	// fica is the employee's and employer's shares. employers don't match the additional medicare tax.
+	calc := func(household *Household) (float64, float64, float64, float64) {
+		b := federal.calcBreakdown(household)
+		stateTax := 0.0
+		if record.state != nil {
+			stateTax = record.state.calcBreakdown(household, federal, nil).tax
+		}
+		return b.tax - b.payrollTax, stateTax, 2*b.payrollTax - federal.calcAdditionalMedicareTax(household), b.payrollTax
+	}
+	result.fiitax, result.siitax, result.fica, result.tfica = calc(record.household)
+
+	// spouseIncome stays the same, so the step is the primary taxpayer's
+	bumped := *record.household
+	bumped.income += marginalStep
+	fiitax, siitax, fica, _ := calc(&bumped)
+	result.frate = 100 * (fiitax - result.fiitax) / marginalStep
+	result.srate = 100 * (siitax - result.siitax) / marginalStep
+	result.ficar = 100 * (fica - result.fica) / marginalStep
+	return result
+}
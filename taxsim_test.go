@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// these are worked by hand from the 2022 law, as TAXSIM calculates them
+func TestCalcTaxsim(t *testing.T) {
+	states := testStates()
+	federal := initializeFederal(&Household{})
+	tests := []struct {
+		name string
+		row  string
+		want TaxsimResult
+	}{
+		{"single", "1,2022,0,1,100000,0,0,0",
+			TaxsimResult{fiitax: 14768, fica: 15300, tfica: 7650, frate: 22, ficar: 15.3}},
+		{"joint, both earning", "2,2022,0,2,100000,100000,0,0",
+			TaxsimResult{fiitax: 29536, fica: 30600, tfica: 15300, frate: 22, ficar: 15.3}},
+		{"joint, one earner over the social security cap", "3,2022,0,2,200000,0,0,0",
+			TaxsimResult{fiitax: 29536, fica: 24028, tfica: 12014, frate: 22, ficar: 2.9}},
+		{"single, over the social security cap", "4,2022,0,1,160000,0,0,0",
+			TaxsimResult{fiitax: 29127.5, fica: 22868, tfica: 11434, frate: 24, ficar: 2.9}},
+		{"single, additional medicare", "7,2022,0,1,300000,0,0,0",
+			TaxsimResult{fiitax: 74220.5, fica: 2*(9114+4350+900) - 900, tfica: 9114 + 4350 + 900, frate: 35, ficar: 3.8}},
+		{"interest is ordinary income", "5,2022,0,1,50000,0,10000,0",
+			TaxsimResult{fiitax: 5968, fica: 7650, tfica: 3825, frate: 22, ficar: 15.3}},
+		{"IL taxes interest", "6,2022,14,1,50000,0,1000,0",
+			TaxsimResult{fiitax: 4360.5, siitax: 0.0495 * (51000 - 2375), fica: 7650, tfica: 3825,
+				frate: 12, srate: 4.95, ficar: 15.3}},
+	}
+	for _, test := range tests {
+		input := "taxsimid,year,state,mstat,pwages,swages,intrec,dividends\n" + test.row + "\n"
+		records := readTaxsim(strings.NewReader(input), states)
+		got := calcTaxsim(records[0], federal)
+		if !within(got.fiitax, test.want.fiitax) || !within(got.siitax, test.want.siitax) ||
+			!within(got.fica, test.want.fica) || !within(got.tfica, test.want.tfica) ||
+			!within(got.frate, test.want.frate) || !within(got.srate, test.want.srate) ||
+			!within(got.ficar, test.want.ficar) {
+			t.Errorf("%s: calcTaxsim() = %+v, want %+v", test.name, *got, test.want)
+		}
+	}
+}
+
+func TestTaxsimDividendsAreQualified(t *testing.T) {
+	states := testStates()
+	federal := initializeFederal(&Household{})
+	input := "taxsimid,year,mstat,pwages,intrec,dividends\n1,2022,1,50000,10000,0\n2,2022,1,50000,0,10000\n"
+	records := readTaxsim(strings.NewReader(input), states)
+	if !records[1].household.qualified || records[1].household.ordinaryInterest != 0 {
+		t.Errorf("dividends: household = %+v, want qualified dividends", *records[1].household)
+	}
+	interest, dividends := calcTaxsim(records[0], federal), calcTaxsim(records[1], federal)
+	if dividends.fiitax >= interest.fiitax {
+		t.Errorf("fiitax on qualified dividends = %.2f, want under %.2f on interest", dividends.fiitax, interest.fiitax)
+	}
+
+	// interest alongside dividends doesn't make the dividends ordinary
+	both := &TaxsimRecord{household: &Household{}}
+	values := map[string]float64{"year": 2022, "mstat": 1, "pwages": 50000, "intrec": 1, "dividends": 10000}
+	if err := fillTaxsimRecord(both, values, states); err != nil {
+		t.Fatalf("fillTaxsimRecord() = %v", err)
+	}
+	if !both.household.qualified || both.household.dividends != 10000 || both.household.ordinaryInterest != 1 {
+		t.Errorf("interest and dividends: household = %+v", *both.household)
+	}
+}
+
+func TestFillTaxsimRecordErrors(t *testing.T) {
+	states := testStates()
+	tests := []struct {
+		name   string
+		values map[string]float64
+	}{
+		{"head of household", map[string]float64{"year": 2022, "mstat": 1, "depx": 2, "pwages": 30000}},
+		{"mstat 0 with dependents", map[string]float64{"year": 2022, "depx": 1}},
+		{"no mstat", map[string]float64{"year": 2022, "pwages": 30000}},
+		{"mstat 0", map[string]float64{"year": 2022, "mstat": 0, "pwages": 30000}},
+		{"married filing separately", map[string]float64{"year": 2022, "mstat": 6, "pwages": 30000}},
+		{"a dependent taxpayer", map[string]float64{"year": 2022, "mstat": 8, "pwages": 3000}},
+		{"spouse wages on a single return", map[string]float64{"year": 2022, "mstat": 1, "swages": 3000}},
+		{"another year", map[string]float64{"year": 2021, "mstat": 1}},
+		{"unknown state", map[string]float64{"year": 2022, "mstat": 1, "state": 52}},
+		{"negative interest", map[string]float64{"year": 2022, "mstat": 1, "intrec": -1}},
+		{"negative spouse wages", map[string]float64{"year": 2022, "mstat": 2, "pwages": 50000, "swages": -1}},
+	}
+	for _, test := range tests {
+		record := &TaxsimRecord{household: &Household{}}
+		if err := fillTaxsimRecord(record, test.values, states); err == nil {
+			t.Errorf("%s: fillTaxsimRecord() = nil, want an error", test.name)
+		}
+	}
+
+	// joint returns with dependents are fine
+	record := &TaxsimRecord{household: &Household{}}
+	values := map[string]float64{"year": 2022, "mstat": 2, "depx": 2, "pwages": 60000}
+	if err := fillTaxsimRecord(record, values, states); err != nil {
+		t.Errorf("joint with dependents: fillTaxsimRecord() = %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	sparkWidth  = 24
+	barWidth    = 20
+	chartWidth  = 64
+	chartHeight = 16
+)
+
+var (
+	sparkChars = []rune("▁▂▃▄▅▆▇█")
+	barEighths = []rune(" ▏▎▍▌▋▊▉")
+	// chart markers by rank
+	chartMarkers = []rune("123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOP")
+)
+
+// sweepRates returns a jurisdiction's effective rates across the sweep; an empty abbrev is federal
+func sweepRates(sweep *Sweep, abbrev string) []float64 {
+	rates := make([]float64, len(sweep.Points))
+	for i, point := range sweep.Points {
+		if abbrev == "" {
+			rates[i] = point.Federal.EffectiveRate
+		} else {
+			rates[i] = point.States[abbrev].EffectiveRate
+		}
+	}
+	return rates
+}
+
+// sample picks n evenly spaced values, repeating some if there are fewer than n
+func sample(values []float64, n int) []float64 {
+	if len(values) == 0 {
+		return values
+	}
+	sampled := make([]float64, n)
+	for i := range sampled {
+		sampled[i] = values[int(math.Round(float64(i)*float64(len(values)-1)/float64(n-1)))]
+	}
+	return sampled
+}
+
+func rateRange(rates ...[]float64) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, r := range rates {
+		for _, rate := range r {
+			lo, hi = math.Min(lo, rate), math.Max(hi, rate)
+		}
+	}
+	if hi <= lo {
+		hi = lo + 0.01
+	}
+	return lo, hi
+}
+
+// sparkline draws the rates scaled to lo-hi, so that sparklines on the same scale can be compared
+func sparkline(rates []float64, lo, hi float64) string {
+	var b strings.Builder
+	for _, rate := range sample(rates, sparkWidth) {
+		level := int(math.Round((rate - lo) / (hi - lo) * float64(len(sparkChars)-1)))
+		b.WriteRune(sparkChars[int(math.Max(0, math.Min(float64(len(sparkChars)-1), float64(level))))])
+	}
+	return b.String()
+}
+
+// bar draws a rate as a bar in eighths of a character, where max fills the bar. negative
+// rates get no bar.
+func bar(rate, max float64) string {
+	eighths := 0
+	if max > 0 {
+		eighths = int(math.Round(math.Max(0, math.Min(1, rate/max)) * barWidth * 8))
+	}
+	s := strings.Repeat("█", eighths/8)
+	if eighths%8 > 0 {
+		s += string(barEighths[eighths%8])
+	}
+	return s
+}
+
+// printLineChart plots the top states' effective rates across the sweep, marking each by its rank
+func printLineChart(sweep *Sweep, states *[51]*State, top int) {
+	if top <= 0 || top > len(states) {
+		top = len(states)
+	}
+	rates := make([][]float64, top)
+	for i, state := range states[:top] {
+		rates[i] = sweepRates(sweep, state.abbrev)
+	}
+	lo, hi := rateRange(rates...)
+	row := func(rate float64) int {
+		return int(math.Round((hi - rate) / (hi - lo) * (chartHeight - 1)))
+	}
+
+	grid := make([][]rune, chartHeight)
+	for i := range grid {
+		grid[i] = []rune(strings.Repeat(" ", chartWidth))
+	}
+	// draw the lowest ranked first, so that the top states are drawn over them
+	for i := top - 1; i >= 0; i-- {
+		previous := -1
+		for col, rate := range sample(rates[i], chartWidth) {
+			r := row(rate)
+			from, to := r, r
+			if previous >= 0 {
+				// fill in steep stretches so the line stays connected
+				from, to = int(math.Min(float64(r), float64(previous))), int(math.Max(float64(r), float64(previous)))
+			}
+			for y := from; y <= to; y++ {
+				grid[y][col] = chartMarkers[i%len(chartMarkers)]
+			}
+			previous = r
+		}
+	}
+
+	fmt.Printf("\nEffective rate by income, the top %d states\n", top)
+	for i, line := range grid {
+		label := ""
+		switch i {
+		case 0:
+			label = fmt.Sprintf("%.1f%%", 100*hi)
+		case chartHeight / 2:
+			label = fmt.Sprintf("%.1f%%", 100*(lo+hi)/2)
+		case chartHeight - 1:
+			label = fmt.Sprintf("%.1f%%", 100*lo)
+		}
+		fmt.Printf("%8s |%s\n", label, string(line))
+	}
+	fmt.Printf("%8s +%s\n", "", strings.Repeat("-", chartWidth))
+	first, last := formatDollars(sweep.Points[0].Income), formatDollars(sweep.Points[len(sweep.Points)-1].Income)
+	fmt.Printf("%8s  %-*s%s\n", "", chartWidth-len(last), first, last)
+	for i, state := range states[:top] {
+		fmt.Printf("  %c %-20s", chartMarkers[i%len(chartMarkers)], state.name)
+		if i%3 == 2 || i == top-1 {
+			fmt.Println()
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+type SortKey int
+
+const (
+	sortByEffective SortKey = iota
+	sortByTax
+	sortByMarginal
+)
+
+var sortNames = [...]string{"effective rate", "tax", "marginal rate"}
+
+type TUIRow struct {
+	state     *State
+	tax       int
+	effective float64
+	marginal  float64 // the state's rate on the next marginalStep of income
+}
+
+// TUI is the state of the interactive ranking. the household is recalculated on every key.
+type TUI struct {
+	household Household
+	step      float64 // how much income, gains and dividends change per key
+	sortBy    SortKey
+	selected  int // row index of the highlighted state
+	offset    int // the first row shown, when the terminal is too short for all 51
+	breakdown bool
+	federal   *Federal
+	rows      []*TUIRow
+}
+
+var tuiHelp = []string{
+	"←/→ income  [/] capital gains  {/} dividends  -/+ dependents  m joint  u qualified",
+	"↑/↓ select  enter breakdown  s sort by effective rate, tax or marginal rate  q quit",
+}
+
+func runTUI(args []string) {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	newHousehold := householdFlags(fs)
+	step := fs.Float64("step", 5000, "How much each key press changes income, capital gains or dividends by")
+	fs.Parse(args)
+	if *step <= 0 {
+		usageError("tui: -step must be positive")
+	}
+
+	restore, err := rawMode()
+	if err != nil {
+		usageError("tui: needs an interactive terminal: %v", err)
+	}
+	// use the alternate screen and hide the cursor, putting both back on the way out
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	tui := &TUI{household: *newHousehold(), step: *step}
+	tui.recalc()
+	buf := make([]byte, 16)
+	for {
+		tui.render()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		// keys held down can arrive together. arrow keys are 3 byte escape sequences.
+		for keys := buf[:n]; len(keys) > 0; {
+			size := 1
+			if len(keys) >= 3 && keys[0] == '\x1b' && keys[1] == '[' {
+				size = 3
+			}
+			if !tui.handleKey(keys[:size]) {
+				return
+			}
+			keys = keys[size:]
+		}
+	}
+}
+
+// rawMode turns off line buffering and echo on the terminal with stty, returning a function
+// that restores its settings
+func rawMode() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(saved) }, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// terminalSize returns the terminal's rows and columns, or 24x80 if stty can't tell
+func terminalSize() (int, int) {
+	var rows, cols int
+	if out, err := stty("size"); err == nil {
+		if n, _ := fmt.Sscan(out, &rows, &cols); n == 2 && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
+
+// handleKey applies a key press, returning false to quit
+func (tui *TUI) handleKey(key []byte) bool {
+	h := &tui.household
+	adjust := func(amount *float64, delta float64) {
+		*amount = math.Max(0, *amount+delta)
+	}
+	switch string(key) {
+	case "q", "\x03", "\x04": // q, ctrl-c, ctrl-d
+		return false
+	case "\x1b[A", "k":
+		tui.selected = int(math.Max(0, float64(tui.selected-1)))
+		return true
+	case "\x1b[B", "j":
+		tui.selected = int(math.Min(float64(len(tui.rows)-1), float64(tui.selected+1)))
+		return true
+	case "\r", "\n", " ":
+		tui.breakdown = !tui.breakdown
+		return true
+	case "\x1b[C", "l":
+		adjust(&h.income, tui.step)
+	case "\x1b[D", "h":
+		adjust(&h.income, -tui.step)
+	case "]":
+		adjust(&h.capitalGains, tui.step)
+	case "[":
+		adjust(&h.capitalGains, -tui.step)
+	case "}":
+		adjust(&h.dividends, tui.step)
+	case "{":
+		adjust(&h.dividends, -tui.step)
+	case "+", "=":
+		h.numDependents++
+	case "-":
+		h.numDependents = int(math.Max(0, float64(h.numDependents-1)))
+	case "m":
+		h.mfj = !h.mfj
+	case "u":
+		h.qualified = !h.qualified
+	case "s":
+		tui.sortBy = (tui.sortBy + 1) % SortKey(len(sortNames))
+	default:
+		return true
+	}
+	// income below pre-tax contributions isn't something the calculation handles
+	h.income = math.Max(h.income, h.pretaxContributions())
+	tui.recalc()
+	return true
+}
+
+// recalc reruns every state for the household and re-sorts, keeping the same state selected
+func (tui *TUI) recalc() {
+	selected := ""
+	if tui.selected < len(tui.rows) {
+		selected = tui.rows[tui.selected].state.abbrev
+	}
+	household := tui.household
+	tui.federal = initializeFederal(&household)
+	states := initializeStates(&household, tui.federal)
+
+	tui.rows = tui.rows[:0]
+	for _, state := range states {
+		tui.rows = append(tui.rows, &TUIRow{
+			state:     state,
+			tax:       state.incomeTax,
+			effective: state.effectiveRate,
+			marginal:  calcStateMarginalRate(&household, tui.federal, state),
+		})
+	}
+	sort.SliceStable(tui.rows, func(i, j int) bool {
+		a, b := tui.rows[i], tui.rows[j]
+		switch tui.sortBy {
+		case sortByTax:
+			return a.tax > b.tax
+		case sortByMarginal:
+			return a.marginal > b.marginal
+		}
+		return a.effective > b.effective
+	})
+	for i, row := range tui.rows {
+		if row.state.abbrev == selected {
+			tui.selected = i
+		}
+	}
+}
+
+func (tui *TUI) render() {
+	height, width := terminalSize()
+	h := &tui.household
+	status := "single"
+	if h.mfj {
+		status = "joint"
+	}
+	dividends := "nonqualified"
+	if h.qualified {
+		dividends = "qualified"
+	}
+	lines := []string{
+		fmt.Sprintf("income $%.0f   capital gains $%.0f   dividends $%.0f (%s)   %s, %d dependents",
+			h.income, h.capitalGains, h.dividends, dividends, status, h.numDependents),
+		fmt.Sprintf("%s: $%d, %.3f%%   sorted by %s", tui.federal.name, tui.federal.incomeTax,
+			100*tui.federal.effectiveRate, sortNames[tui.sortBy]),
+	}
+	lines = append(append(lines, tuiHelp...), "")
+
+	highlight := -1
+	if tui.breakdown {
+		row := tui.rows[tui.selected]
+		worksheet := &Worksheet{}
+		b := row.state.calcBreakdown(h, tui.federal, worksheet)
+		lines = append(lines, worksheetLines(h, row.state, worksheet, b)...)
+	} else {
+		lines = append(lines, "    State                Tax        Effective  Marginal")
+		visible := height - len(lines) - 1
+		if visible < 1 {
+			visible = 1
+		}
+		// scroll just enough to keep the selection on screen
+		if tui.selected < tui.offset {
+			tui.offset = tui.selected
+		} else if tui.selected >= tui.offset+visible {
+			tui.offset = tui.selected - visible + 1
+		}
+		for i := tui.offset; i < len(tui.rows) && i < tui.offset+visible; i++ {
+			row := tui.rows[i]
+			line := fmt.Sprintf("%-3d %-20s $%-9d %-10s %s", i+1, row.state.name, row.tax,
+				fmt.Sprintf("%.3f%%", 100*row.effective), fmt.Sprintf("%.2f%%", 100*row.marginal))
+			if i == tui.selected {
+				highlight = len(lines)
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if i >= height {
+			break
+		}
+		if len([]rune(line)) > width {
+			line = string([]rune(line)[:width])
+		}
+		if i == highlight {
+			line = "\x1b[7m" + line + "\x1b[0m" // reverse video
+		}
+		screen.WriteString(line)
+		// raw mode doesn't turn \n into \r\n
+		screen.WriteString("\r\n")
+	}
+	fmt.Print(screen.String())
+}
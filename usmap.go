@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// tileGrid places each state on a grid (column, row) roughly where it is on a map of the
+// US, so that every state gets the same area however small it is
+var tileGrid = map[string][2]int{
+	"ME": {11, 0},
+	"AK": {0, 1}, "WI": {6, 1}, "VT": {10, 1}, "NH": {11, 1},
+	"WA": {1, 2}, "ID": {2, 2}, "MT": {3, 2}, "ND": {4, 2}, "MN": {5, 2}, "IL": {6, 2}, "MI": {7, 2},
+	"NY": {9, 2}, "MA": {10, 2},
+	"OR": {1, 3}, "NV": {2, 3}, "WY": {3, 3}, "SD": {4, 3}, "IA": {5, 3}, "IN": {6, 3}, "OH": {7, 3},
+	"PA": {8, 3}, "NJ": {9, 3}, "CT": {10, 3}, "RI": {11, 3},
+	"CA": {1, 4}, "UT": {2, 4}, "CO": {3, 4}, "NE": {4, 4}, "MO": {5, 4}, "KY": {6, 4}, "WV": {7, 4},
+	"VA": {8, 4}, "MD": {9, 4}, "DE": {10, 4},
+	"AZ": {2, 5}, "NM": {3, 5}, "KS": {4, 5}, "AR": {5, 5}, "TN": {6, 5}, "NC": {7, 5}, "SC": {8, 5},
+	"DC": {9, 5},
+	"OK": {4, 6}, "LA": {5, 6}, "MS": {6, 6}, "AL": {7, 6}, "GA": {8, 6},
+	"HI": {0, 7}, "TX": {4, 7}, "FL": {9, 7},
+}
+
+const (
+	tileSize = 60.0
+	tileGap  = 4.0
+)
+
+// the ends of the map's color scale, from the lowest rate to the highest
+var (
+	mapLow  = color.RGBA{239, 243, 255, 255}
+	mapHigh = color.RGBA{8, 48, 107, 255}
+)
+
+// mapColor interpolates the color scale, t being 0 for the lowest rate and 1 for the highest
+func mapColor(t float64) color.RGBA {
+	t = math.Max(0, math.Min(1, t))
+	mix := func(a, b uint8) uint8 { return uint8(math.Round(float64(a) + t*(float64(b)-float64(a)))) }
+	return color.RGBA{mix(mapLow.R, mapHigh.R), mix(mapLow.G, mapHigh.G), mix(mapLow.B, mapHigh.B), 255}
+}
+
+// writeMap renders a tile map of the states colored by their effective or marginal rate to
+// an .svg or .png file
+func writeMap(filename string, household *Household, federal *Federal, states *[51]*State,
+	marginal bool, labels bool) {
+	rates := make(map[string]float64, len(states))
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, state := range states {
+		rate := state.effectiveRate
+		if marginal {
+			rate = calcStateMarginalRate(household, federal, state)
+		}
+		rates[state.abbrev] = rate
+		lo, hi = math.Min(lo, rate), math.Max(hi, rate)
+	}
+	if hi <= lo {
+		hi = lo + 0.01
+	}
+	kind := "Effective"
+	if marginal {
+		kind = "Marginal"
+	}
+	title := fmt.Sprintf("%s state income tax rate at $%.0f of income", kind, household.income)
+	width, height := 12*(tileSize+tileGap)+40, 8*(tileSize+tileGap)+150
+	writeCanvas(filename, int(width), int(height), func(c Canvas) {
+		drawMap(c, width, height, title, rates, lo, hi, labels)
+	})
+}
+
+func drawMap(c Canvas, width, height float64, title string, rates map[string]float64, lo, hi float64, labels bool) {
+	c.rect(0, 0, width, height, white)
+	c.text(width/2, 30, title, 16, black, anchorMiddle)
+	left, top := 20.0, 50.0
+	abbrevs := make([]string, 0, len(rates))
+	for abbrev := range rates {
+		abbrevs = append(abbrevs, abbrev)
+	}
+	sort.Strings(abbrevs) // so the SVG is the same every time
+	for _, abbrev := range abbrevs {
+		rate := rates[abbrev]
+		cell := tileGrid[abbrev]
+		x, y := left+float64(cell[0])*(tileSize+tileGap), top+float64(cell[1])*(tileSize+tileGap)
+		t := (rate - lo) / (hi - lo)
+		c.rect(x, y, tileSize, tileSize, mapColor(t))
+		if labels {
+			ink := black
+			if t > 0.55 {
+				ink = white
+			}
+			c.text(x+tileSize/2, y+tileSize/2-2, abbrev, 14, ink, anchorMiddle)
+			c.text(x+tileSize/2, y+tileSize/2+16, fmt.Sprintf("%.1f%%", 100*rate), 11, ink, anchorMiddle)
+		}
+	}
+
+	// the legend is the color scale in 40 slices, with the lowest, middle and highest rates
+	legendLeft, legendTop, legendWidth := width/2-160, height-70, 320.0
+	for i := 0; i < 40; i++ {
+		c.rect(legendLeft+legendWidth*float64(i)/40, legendTop, legendWidth/40+0.5, 14, mapColor((float64(i)+0.5)/40))
+	}
+	for i, t := range []float64{0, 0.5, 1} {
+		anchor := []TextAnchor{anchorStart, anchorMiddle, anchorEnd}[i]
+		c.text(legendLeft+legendWidth*t, legendTop+32, fmt.Sprintf("%.2f%%", 100*(lo+t*(hi-lo))), 11, gray, anchor)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// svgDocument is the part of the SVG the map tests look at
+type svgDocument struct {
+	Rects []struct {
+		X    float64 `xml:"x,attr"`
+		Y    float64 `xml:"y,attr"`
+		Fill string  `xml:"fill,attr"`
+	} `xml:"rect"`
+	Texts []struct {
+		Text string `xml:",chardata"`
+	} `xml:"text"`
+}
+
+func TestWriteMap(t *testing.T) {
+	household := &Household{income: 100000}
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+	filename := filepath.Join(t.TempDir(), "map.svg")
+	writeMap(filename, household, federal, states, false, true)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc svgDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("the map isn't valid SVG: %v", err)
+	}
+	texts := make(map[string]int)
+	for _, text := range doc.Texts {
+		texts[text.Text]++
+	}
+
+	lo, hi := 1.0, 0.0
+	tiles := make(map[[2]int]string)
+	for _, state := range states {
+		if texts[state.abbrev] != 1 {
+			t.Errorf("%s is labeled %d times, want once", state.abbrev, texts[state.abbrev])
+		}
+		if label := fmt.Sprintf("%.1f%%", 100*state.effectiveRate); texts[label] == 0 {
+			t.Errorf("%s's rate %s isn't labeled", state.abbrev, label)
+		}
+		cell, ok := tileGrid[state.abbrev]
+		if !ok {
+			t.Errorf("%s has no tile", state.abbrev)
+		}
+		if other, taken := tiles[cell]; taken {
+			t.Errorf("%s and %s share the tile at %v", state.abbrev, other, cell)
+		}
+		tiles[cell] = state.abbrev
+		lo, hi = math.Min(lo, state.effectiveRate), math.Max(hi, state.effectiveRate)
+	}
+	if len(tileGrid) != len(states) {
+		t.Errorf("tileGrid has %d tiles for %d states", len(tileGrid), len(states))
+	}
+
+	// the background, a tile per state and the legend's 40 slices
+	if len(doc.Rects) != 1+len(states)+40 {
+		t.Errorf("the map has %d rects, want %d", len(doc.Rects), 1+len(states)+40)
+	}
+	for _, rate := range []float64{lo, (lo + hi) / 2, hi} {
+		if label := fmt.Sprintf("%.2f%%", 100*rate); texts[label] == 0 {
+			t.Errorf("the legend doesn't show %s", label)
+		}
+	}
+	// the states with no income tax are the lightest tile, and the highest rate the darkest
+	fills := make(map[string]bool)
+	for _, rect := range doc.Rects[1 : 1+len(states)] {
+		fills[rect.Fill] = true
+	}
+	for _, c := range []string{svgColor(mapLow), svgColor(mapHigh)} {
+		if !fills[c] {
+			t.Errorf("no tile is colored %s", c)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+)
+
+// the amount of extra interest used to measure marginal rates
+const marginalStep = 1000.0
+
+type BondYields struct {
+	state         *State
+	marginalRate  float64 // combined federal + state rate on fully taxable (ordinary) interest
+	treasury      float64 // tax-equivalent yields
+	inStateMuni   float64
+	otherMuni     float64
+	treasuryRate  float64 // combined marginal rates on each kind of interest
+	inStateRate   float64
+	otherMuniRate float64
+}
+
+func runYield(args []string) {
+	fs := flag.NewFlagSet("yield", flag.ExitOnError)
+	newHousehold := householdFlags(fs)
+	bondYield := fs.Float64("yield", 0, "Yield of the bond, e.g. 0.04 (required)")
+	treasuryYield := fs.Float64("treasuryYield", 0, "Yield of the treasury, if different from -yield")
+	muniYield := fs.Float64("muniYield", 0, "Yield of the in-state muni, if different from -yield")
+	otherMuniYield := fs.Float64("otherMuniYield", 0, "Yield of the out-of-state muni, if different from -yield")
+	fs.Parse(args)
+	if *bondYield <= 0 {
+		usageError("yield: -yield must be positive, e.g. -yield=0.04")
+	}
+	for _, y := range []*float64{treasuryYield, muniYield, otherMuniYield} {
+		if *y == 0 {
+			*y = *bondYield
+		}
+	}
+
+	household := newHousehold()
+	federal := initializeFederal(household)
+	states := initializeStates(household, federal)
+
+	yields := make([]*BondYields, 0, len(states))
+	for _, state := range states {
+		yields = append(yields, calcBondYields(household, federal, state, *treasuryYield, *muniYield, *otherMuniYield))
+	}
+	sort.SliceStable(yields, func(i, j int) bool {
+		return yields[i].inStateMuni > yields[j].inStateMuni
+	})
+	printYields(household, yields, *treasuryYield, *muniYield, *otherMuniYield)
+}
+
+// calcBondYields returns the yield a fully taxable bond would need to match each kind of bond
+// in the given state, using the household's true combined marginal rates.
+func calcBondYields(household *Household, federal *Federal, state *State,
+	treasuryYield, muniYield, otherMuniYield float64) *BondYields {
+	yields := &BondYields{
+		state:         state,
+		marginalRate:  calcMarginalRate(household, federal, state, func(h *Household) *float64 { return &h.ordinaryInterest }),
+		treasuryRate:  calcMarginalRate(household, federal, state, func(h *Household) *float64 { return &h.treasuryInterest }),
+		inStateRate:   calcMarginalRate(household, federal, state, func(h *Household) *float64 { return &h.inStateMuniInterest }),
+		otherMuniRate: calcMarginalRate(household, federal, state, func(h *Household) *float64 { return &h.otherMuniInterest }),
+	}
+	taxEquivalent := func(bondYield, rate float64) float64 {
+		return bondYield * (1 - rate) / (1 - yields.marginalRate)
+	}
+	yields.treasury = taxEquivalent(treasuryYield, yields.treasuryRate)
+	yields.inStateMuni = taxEquivalent(muniYield, yields.inStateRate)
+	yields.otherMuni = taxEquivalent(otherMuniYield, yields.otherMuniRate)
+	return yields
+}
+
+// calcMarginalRate returns the combined federal and state tax on the next `marginalStep`
+// dollars of the income that `field` points to, as a rate.
+func calcMarginalRate(household *Household, federal *Federal, state *State,
+	field func(*Household) *float64) float64 {
+	bumped := *household
+	*field(&bumped) += marginalStep
+	return (calcCombinedTax(&bumped, federal, state) - calcCombinedTax(household, federal, state)) / marginalStep
+}
+
+// calcStateMarginalRate returns the state tax on the next `marginalStep` dollars of wages, as a rate.
+func calcStateMarginalRate(household *Household, federal *Federal, state *State) float64 {
+	bumped := *household
+	bumped.income += marginalStep
+	return (state.calcBreakdown(&bumped, federal, nil).tax - state.calcBreakdown(household, federal, nil).tax) / marginalStep
+}
+
+// calcCombinedTax returns the household's federal plus state tax. it isn't rounded to the
+// dollar, so that differences of it give exact marginal rates.
+func calcCombinedTax(household *Household, federal *Federal, state *State) float64 {
+	return federal.calcBreakdown(household).tax + state.calcBreakdown(household, federal, nil).tax
+}
+
+func printYields(household *Household, yields []*BondYields, treasuryYield, muniYield, otherMuniYield float64) {
+	fmt.Printf("\nTax-equivalent yields for income of $%.0f: treasury %.3f%%, in-state muni %.3f%%, out-of-state muni %.3f%%\n",
+		household.income, 100*treasuryYield, 100*muniYield, 100*otherMuniYield)
+	fmt.Println("    State                Marginal  Treasury  In-state  Out-of-state")
+	fmt.Println("====================================================================")
+	for i, y := range yields {
+		fmt.Printf("%-3d %-20s %-9s %-9s %-9s %s\n", i+1, y.state.name,
+			fmt.Sprintf("%.2f%%", 100*y.marginalRate), fmt.Sprintf("%.3f%%", 100*y.treasury),
+			fmt.Sprintf("%.3f%%", 100*y.inStateMuni), fmt.Sprintf("%.3f%%", 100*y.otherMuni))
+	}
+	fmt.Println("====================================================================")
+}
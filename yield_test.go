@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestCalcBondYieldsMarginalRates(t *testing.T) {
+	tests := []struct {
+		name                       string
+		state                      string
+		household                  Household
+		marginalRate, treasuryRate float64
+	}{
+		// $87,050 of taxable income is in the 22% bracket
+		{"no state tax", "TX", Household{income: 100000}, 0.22, 0.22},
+		{"flat state tax", "IL", Household{income: 100000}, 0.22 + 0.0495, 0.22},
+		// taxable interest is ordinary income even when the dividends are qualified
+		{"qualified dividends", "TX", Household{income: 90000, dividends: 10000, qualified: true}, 0.22, 0.22},
+		{"qualified dividends, flat state tax", "IL", Household{income: 90000, dividends: 10000, qualified: true},
+			0.22 + 0.0495, 0.22},
+	}
+	for _, test := range tests {
+		federal := initializeFederal(&test.household)
+		state := findState(initializeStates(&test.household, federal), test.state)
+		yields := calcBondYields(&test.household, federal, state, 0.04, 0.03, 0.03)
+		if !within(yields.marginalRate*1e4, test.marginalRate*1e4) || !within(yields.treasuryRate*1e4, test.treasuryRate*1e4) {
+			t.Errorf("%s: marginal rates = %g and %g on treasuries, want %g and %g", test.name,
+				yields.marginalRate, yields.treasuryRate, test.marginalRate, test.treasuryRate)
+		}
+	}
+}